@@ -6,10 +6,13 @@ import (
 
 	"github.com/22Fariz22/forum/config"
 	"github.com/22Fariz22/forum/graph"
+	"github.com/22Fariz22/forum/internal/auth"
 	"github.com/22Fariz22/forum/internal/repository"
 	"github.com/22Fariz22/forum/internal/server"
+	"github.com/22Fariz22/forum/internal/validation"
 	"github.com/22Fariz22/forum/pkg/db/postgres"
 	"github.com/22Fariz22/forum/pkg/logger"
+	"github.com/22Fariz22/forum/pubsub"
 	"github.com/jmoiron/sqlx"
 )
 
@@ -24,7 +27,22 @@ func main() {
 	appLogger.InitLogger()
 	appLogger.Infof("AppVersion:%s, LogLevel:%s, Mode:%s", cfg.Server.AppVersion, cfg.Logger.Level, cfg.Server.Mode)
 
+	overflow, err := pubsub.ParseOverflowPolicy(cfg.PubSub.Overflow)
+	if err != nil {
+		appLogger.Fatalf("PubSub config: %s", err)
+	}
+	brokersOpts := graph.BrokersOptions{
+		Queue: pubsub.Options{
+			BufferSize: cfg.PubSub.BufferSize,
+			Overflow:   overflow,
+		},
+		PresenceDebounce:  cfg.PubSub.PresenceDebounce,
+		TypingTTL:         cfg.PubSub.TypingTTL,
+		TypingMinInterval: cfg.PubSub.TypingMinInterval,
+	}
+
 	var repo graph.Repository
+	var brokers *graph.Brokers
 	var psqlDB *sqlx.DB
 
 	if cfg.Storage.StorageType == "postgres" {
@@ -62,20 +80,47 @@ func main() {
 		}
 		defer psqlDB.Close()
 
-		repo, err = repository.NewPostgresRepository(psqlDB)
+		repo, err = repository.NewPostgresRepository(psqlDB, cfg.Server.CtxTimeout)
 		if err != nil {
 			fmt.Println("err in ping", err)
 			return
 		}
 
+		// События подписок рассылаются всем репликам через LISTEN/NOTIFY
+		brokers, err = graph.NewPostgresBrokers(psqlDB, dsn, repo, appLogger, brokersOpts)
+		if err != nil {
+			appLogger.Fatalf("PubSub init: %s", err)
+		}
+
 	} else {
 		appLogger.Infof("storage:inmemory")
 
 		repo = repository.NewInMemoryRepository()
+		brokers = graph.NewInMemoryBrokers(brokersOpts)
+	}
+	defer brokers.Close()
+
+	validator, err := validation.New(validation.Limits{
+		UsernameMinLength:    cfg.Validation.UsernameMinLength,
+		UsernameMaxLength:    cfg.Validation.UsernameMaxLength,
+		UsernamePattern:      cfg.Validation.UsernamePattern,
+		TitleMaxLength:       cfg.Validation.TitleMaxLength,
+		PostMaxLength:        cfg.Validation.PostMaxLength,
+		CommentMaxLength:     cfg.Validation.CommentMaxLength,
+		SearchQueryMaxLength: cfg.Validation.SearchQueryMaxLength,
+		Reactions:            cfg.Validation.Reactions,
+
+		ForumNameMaxLength:        cfg.Validation.ForumNameMaxLength,
+		ForumDescriptionMaxLength: cfg.Validation.ForumDescriptionMaxLength,
+		TagMaxLength:              cfg.Validation.TagMaxLength,
+		TagsMaxCount:              cfg.Validation.TagsMaxCount,
+	})
+	if err != nil {
+		appLogger.Fatalf("Validation config: %s", err)
 	}
 
 	// Инициализируем резолвер с хранилищем и системой pubsub для подписок
-	resolver := graph.NewResolver(repo)
+	resolver := graph.NewResolver(repo, brokers, auth.NewRoles(cfg.Auth.ModeratorIDs, cfg.Auth.AdminIDs), validator)
 
 	s := server.NewServer(appLogger, cfg, resolver)
 	s.Run() //сделать возврат ошибки
@@ -0,0 +1,36 @@
+// Команда recount пересчитывает счётчики комментариев, ответов и голосов в Postgres.
+// Нужна после миграции, добавившей счётчики, и после ручной правки данных.
+package main
+
+import (
+	"context"
+	"log"
+
+	"github.com/22Fariz22/forum/config"
+	"github.com/22Fariz22/forum/internal/repository"
+	"github.com/22Fariz22/forum/pkg/db/postgres"
+	"github.com/22Fariz22/forum/pkg/logger"
+)
+
+func main() {
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		log.Fatalf("Failed to load config: %v", err)
+	}
+
+	appLogger := logger.NewApiLogger(cfg)
+	appLogger.InitLogger()
+
+	psqlDB, err := postgres.NewPsqlDB(cfg)
+	if err != nil {
+		appLogger.Fatalf("Postgresql init: %s", err)
+	}
+	defer psqlDB.Close()
+
+	stats, err := repository.RecountCounters(context.Background(), psqlDB)
+	if err != nil {
+		appLogger.Fatalf("Recount failed: %s", err)
+	}
+
+	appLogger.Infof("Counters recounted: posts updated=%d, comments updated=%d", stats.Posts, stats.Comments)
+}
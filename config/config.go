@@ -4,6 +4,7 @@ import (
 	"log"
 	"os"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/joho/godotenv"
@@ -14,6 +15,9 @@ type Config struct {
 	Server     ServerConfig
 	Middleware MiddlewareConfig
 	Storage    StorageConfig
+	PubSub     PubSubConfig
+	Auth       AuthConfig
+	Validation ValidationConfig
 	Postgres   PostgresConfig
 	Logger     Logger
 }
@@ -30,6 +34,10 @@ type ServerConfig struct {
 	MaxHeaderBytes    int
 	CtxTimeout        time.Duration
 	Debug             bool
+	// Интервал keepalive-пингов для WebSocket-подписок
+	WsKeepAliveInterval time.Duration
+	// Интервал heartbeat-комментариев для подписок через SSE
+	SseKeepAliveInterval time.Duration
 }
 
 // Middleware config struct
@@ -55,6 +63,46 @@ type StorageConfig struct {
 	StorageType string
 }
 
+// PubSub config: очереди подписчиков
+type PubSubConfig struct {
+	BufferSize int
+	Overflow   string // drop-oldest, drop-newest или disconnect
+	// Не чаще одного обновления присутствия на пост за этот интервал
+	PresenceDebounce time.Duration
+	// Индикатор набора гаснет, если его не обновили за TypingTTL
+	TypingTTL time.Duration
+	// Минимальный интервал между запросами typing от одного пользователя
+	TypingMinInterval time.Duration
+}
+
+// Auth config: роли пользователей
+type AuthConfig struct {
+	// ID пользователей-модераторов
+	ModeratorIDs []string
+	// ID администраторов: управляют разделами форума и модерируют
+	AdminIDs []string
+}
+
+// Validation config: ограничения пользовательского ввода, длины в символах Unicode.
+// Имя и заголовок не могут быть длиннее столбцов users.username (20) и posts.title (50)
+type ValidationConfig struct {
+	UsernameMinLength int
+	UsernameMaxLength int
+	UsernamePattern   string
+	TitleMaxLength    int
+	PostMaxLength     int
+	CommentMaxLength  int
+	// Длина поискового запроса
+	SearchQueryMaxLength int
+	// Разрешённые эмодзи-реакции
+	Reactions []string
+	// Название раздела не длиннее столбца forums.name (50)
+	ForumNameMaxLength        int
+	ForumDescriptionMaxLength int
+	TagMaxLength              int
+	TagsMaxCount              int
+}
+
 // Postgresql config
 type PostgresConfig struct {
 	PostgresqlHost     string
@@ -75,16 +123,18 @@ func LoadConfig() (*Config, error) {
 
 	return &Config{
 		Server: ServerConfig{
-			AppVersion:        getEnv("APP_VERSION", "1.0.0"),
-			BaseUrl:           getEnv("SERVER_BASE_URL", "localhost"),
-			Port:              getEnv("SERVER_PORT", "8080"),
-			Mode:              getEnv("MODE", "Development"),
-			ReadTimeout:       getEnvAsDuration("READ_TIMEOUT", 10*time.Second),
-			WriteTimeout:      getEnvAsDuration("WRITE_TIMEOUT", 10*time.Second),
-			CtxDefaultTimeout: getEnvAsDuration("CTX_DEFAULT_TIMEOUT", 12*time.Second),
-			MaxHeaderBytes:    getEnvAsInt("MAX_HEADER_BYTES", 1<<20),
-			CtxTimeout:        getEnvAsDuration("CTX_TIMEOUT", 5*time.Second),
-			Debug:             getEnvAsBool("DEBUG", false),
+			AppVersion:           getEnv("APP_VERSION", "1.0.0"),
+			BaseUrl:              getEnv("SERVER_BASE_URL", "localhost"),
+			Port:                 getEnv("SERVER_PORT", "8080"),
+			Mode:                 getEnv("MODE", "Development"),
+			ReadTimeout:          getEnvAsDuration("READ_TIMEOUT", 10*time.Second),
+			WriteTimeout:         getEnvAsDuration("WRITE_TIMEOUT", 10*time.Second),
+			CtxDefaultTimeout:    getEnvAsDuration("CTX_DEFAULT_TIMEOUT", 12*time.Second),
+			MaxHeaderBytes:       getEnvAsInt("MAX_HEADER_BYTES", 1<<20),
+			CtxTimeout:           getEnvAsDuration("CTX_TIMEOUT", 5*time.Second),
+			Debug:                getEnvAsBool("DEBUG", false),
+			WsKeepAliveInterval:  getEnvAsDuration("WS_KEEPALIVE_INTERVAL", 10*time.Second),
+			SseKeepAliveInterval: getEnvAsDuration("SSE_KEEPALIVE_INTERVAL", 15*time.Second),
 		},
 		Middleware: MiddlewareConfig{
 			MiddlewareStackSize:         getEnvAsInt("MIDDLEWARE_STACK_SIZE", 1024), // Default to 1024 (1 << 10)
@@ -103,6 +153,32 @@ func LoadConfig() (*Config, error) {
 		Storage: StorageConfig{
 			StorageType: getEnv("STORAGE_TYPE", "inmemory"),
 		},
+		PubSub: PubSubConfig{
+			BufferSize:        getEnvAsInt("PUBSUB_BUFFER_SIZE", 16),
+			Overflow:          getEnv("PUBSUB_OVERFLOW", "drop-oldest"),
+			PresenceDebounce:  getEnvAsDuration("PRESENCE_DEBOUNCE", time.Second),
+			TypingTTL:         getEnvAsDuration("TYPING_TTL", 5*time.Second),
+			TypingMinInterval: getEnvAsDuration("TYPING_MIN_INTERVAL", time.Second),
+		},
+		Auth: AuthConfig{
+			ModeratorIDs: getEnvAsSlice("MODERATOR_IDS", nil),
+			AdminIDs:     getEnvAsSlice("ADMIN_IDS", nil),
+		},
+		Validation: ValidationConfig{
+			UsernameMinLength:    getEnvAsInt("USERNAME_MIN_LENGTH", 2),
+			UsernameMaxLength:    getEnvAsInt("USERNAME_MAX_LENGTH", 20),
+			UsernamePattern:      getEnv("USERNAME_PATTERN", `^[\p{L}\p{N}_.-]+$`),
+			TitleMaxLength:       getEnvAsInt("TITLE_MAX_LENGTH", 50),
+			PostMaxLength:        getEnvAsInt("POST_MAX_LENGTH", 20000),
+			CommentMaxLength:     getEnvAsInt("COMMENT_MAX_LENGTH", 2000),
+			SearchQueryMaxLength: getEnvAsInt("SEARCH_QUERY_MAX_LENGTH", 200),
+			Reactions:            getEnvAsSlice("REACTIONS", []string{"👍", "❤️", "😂", "🎉", "😮", "😢"}),
+
+			ForumNameMaxLength:        getEnvAsInt("FORUM_NAME_MAX_LENGTH", 50),
+			ForumDescriptionMaxLength: getEnvAsInt("FORUM_DESCRIPTION_MAX_LENGTH", 500),
+			TagMaxLength:              getEnvAsInt("TAG_MAX_LENGTH", 32),
+			TagsMaxCount:              getEnvAsInt("TAGS_MAX_COUNT", 5),
+		},
 		Postgres: PostgresConfig{
 			PostgresqlHost:     getEnv("POSTGRES_HOST", "localhost"),
 			PostgresqlPort:     getEnv("POSTGRES_PORT", "5432"),
@@ -146,3 +222,19 @@ func getEnvAsInt(key string, defaultValue int) int {
 	}
 	return defaultValue
 }
+
+// getEnvAsSlice разбирает список значений через запятую
+func getEnvAsSlice(key string, defaultValue []string) []string {
+	valStr := getEnv(key, "")
+	if valStr == "" {
+		return defaultValue
+	}
+
+	var values []string
+	for _, v := range strings.Split(valStr, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			values = append(values, v)
+		}
+	}
+	return values
+}
@@ -0,0 +1,161 @@
+package graph
+
+import (
+	"context"
+	"time"
+
+	graphModel "github.com/22Fariz22/forum/graph/model"
+	"github.com/22Fariz22/forum/pkg/logger"
+	"github.com/22Fariz22/forum/pubsub"
+	"github.com/jmoiron/sqlx"
+)
+
+// Каналы NOTIFY, общие для всех реплик API
+const (
+	commentsChannel  = "forum_comments"
+	postsChannel     = "forum_posts"
+	typingChannel    = "forum_typing"
+	reactionsChannel = "forum_reactions"
+)
+
+// Brokers – брокеры событий, на которых построены подписки
+type Brokers struct {
+	Comments pubsub.Broker[*graphModel.Comment]
+	Posts    pubsub.Broker[*graphModel.Post]
+
+	// Присутствие считается по подпискам своего процесса, поэтому
+	// и трекер, и его брокер всегда работают в памяти
+	Presence       *pubsub.Presence
+	PresenceEvents pubsub.Broker[*graphModel.PostPresence]
+
+	// Индикаторы набора гаснут на той реплике, где их включили,
+	// а события о них расходятся через TypingEvents
+	Typing       *pubsub.Typing
+	TypingEvents pubsub.Broker[*graphModel.TypingEvent]
+
+	// События реакций невелики и уходят целиком, без повторного чтения из хранилища
+	Reactions pubsub.Broker[*graphModel.ReactionsChangedEvent]
+}
+
+// BrokersOptions – настройки брокеров событий
+type BrokersOptions struct {
+	Queue             pubsub.Options
+	PresenceDebounce  time.Duration
+	TypingTTL         time.Duration
+	TypingMinInterval time.Duration
+}
+
+// NewInMemoryBrokers создаёт брокеры, работающие в пределах одного процесса
+func NewInMemoryBrokers(opts BrokersOptions) *Brokers {
+	b := &Brokers{
+		Comments:     pubsub.New[*graphModel.Comment](opts.Queue),
+		Posts:        pubsub.New[*graphModel.Post](opts.Queue),
+		TypingEvents: pubsub.New[*graphModel.TypingEvent](opts.Queue),
+		Reactions:    pubsub.New[*graphModel.ReactionsChangedEvent](opts.Queue),
+	}
+	b.initPresence(opts)
+	b.initTyping(opts)
+
+	return b
+}
+
+// initTyping создаёт трекер индикаторов набора поверх TypingEvents
+func (b *Brokers) initTyping(opts BrokersOptions) {
+	b.Typing = pubsub.NewTyping(opts.TypingTTL, opts.TypingMinInterval, func(event pubsub.TypingEvent) {
+		b.TypingEvents.Publish(typingTopic(event.PostID), toGraphTypingEvent(event))
+	})
+}
+
+// initPresence создаёт трекер присутствия, публикующий сглаженные изменения
+func (b *Brokers) initPresence(opts BrokersOptions) {
+	events := pubsub.New[*graphModel.PostPresence](opts.Queue)
+
+	b.PresenceEvents = events
+	b.Presence = pubsub.NewPresence(opts.PresenceDebounce, func(snapshot pubsub.PresenceSnapshot) {
+		events.Publish(presenceTopic(snapshot.Topic), toGraphPresence(snapshot))
+	})
+}
+
+// NewPostgresBrokers создаёт брокеры поверх LISTEN/NOTIFY, чтобы события
+// доходили до подписчиков, подключённых к любой реплике
+func NewPostgresBrokers(db *sqlx.DB, dsn string, repo Repository, logger logger.Logger, opts BrokersOptions) (*Brokers, error) {
+	comments, err := pubsub.NewPostgres(db, dsn, logger, pubsub.PostgresOptions[*graphModel.Comment]{
+		Channel: commentsChannel,
+		Local:   opts.Queue,
+		ID: func(c *graphModel.Comment) string {
+			return c.ID
+		},
+		Fetch: func(ctx context.Context, id string) (*graphModel.Comment, error) {
+			c, err := repo.GetCommentByID(ctx, id)
+			if err != nil {
+				return nil, err
+			}
+			return toGraphComment(c), nil
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	posts, err := pubsub.NewPostgres(db, dsn, logger, pubsub.PostgresOptions[*graphModel.Post]{
+		Channel: postsChannel,
+		Local:   opts.Queue,
+		ID: func(p *graphModel.Post) string {
+			return p.ID
+		},
+		Fetch: func(ctx context.Context, id string) (*graphModel.Post, error) {
+			p, err := repo.GetPostByID(ctx, id)
+			if err != nil {
+				return nil, err
+			}
+			return toGraphPost(p), nil
+		},
+	})
+	if err != nil {
+		comments.Close()
+		return nil, err
+	}
+
+	typing, err := pubsub.NewPostgres(db, dsn, logger, pubsub.PostgresOptions[*graphModel.TypingEvent]{
+		Channel: typingChannel,
+		Local:   opts.Queue,
+	})
+	if err != nil {
+		comments.Close()
+		posts.Close()
+		return nil, err
+	}
+
+	reactions, err := pubsub.NewPostgres(db, dsn, logger, pubsub.PostgresOptions[*graphModel.ReactionsChangedEvent]{
+		Channel: reactionsChannel,
+		Local:   opts.Queue,
+	})
+	if err != nil {
+		comments.Close()
+		posts.Close()
+		typing.Close()
+		return nil, err
+	}
+
+	b := &Brokers{
+		Comments:     comments,
+		Posts:        posts,
+		TypingEvents: typing,
+		Reactions:    reactions,
+	}
+	b.initPresence(opts)
+	b.initTyping(opts)
+
+	return b, nil
+}
+
+// Close останавливает все брокеры
+func (b *Brokers) Close() {
+	b.Comments.Close()
+	b.Posts.Close()
+	b.Presence.Close()
+	b.PresenceEvents.Close()
+	b.Typing.Close()
+	b.TypingEvents.Close()
+	b.Reactions.Close()
+}
@@ -0,0 +1,398 @@
+package graph
+
+import (
+	"html"
+	"strings"
+
+	graphModel "github.com/22Fariz22/forum/graph/model"
+	commonModel "github.com/22Fariz22/forum/internal/model"
+	"github.com/22Fariz22/forum/pkg/diff"
+	"github.com/22Fariz22/forum/pkg/search"
+	"github.com/22Fariz22/forum/pubsub"
+)
+
+// toGraphComment преобразует комментарий из доменной модели в GraphQL-модель
+func toGraphComment(c *commonModel.Comment) *graphModel.Comment {
+	comment := &graphModel.Comment{
+		ID:           c.ID,
+		PostID:       c.PostID,
+		ParentID:     c.ParentID,
+		Content:      c.Content,
+		HaveComments: c.ReplyCount > 0,
+		ReplyCount:   int32(c.ReplyCount),
+		Score:        int32(c.Score),
+		Upvotes:      int32(c.Upvotes),
+		Downvotes:    int32(c.Downvotes),
+		CreatedAt:    c.CreatedAt,
+		Cursor:       commonModel.CursorOf(c).Encode(),
+		Depth:        int32(c.Depth),
+		EditedAt:     c.EditedAt,
+		DeletedAt:    c.DeletedAt,
+	}
+
+	if c.EditedAt != nil {
+		editCursor := commonModel.EditCursorOf(c).Encode()
+		comment.EditCursor = &editCursor
+	}
+
+	if c.Author != nil {
+		comment.Author = &graphModel.User{
+			ID:        c.Author.ID,
+			Username:  c.Author.Username,
+			CreatedAt: c.Author.CreatedAt,
+		}
+	} else {
+		comment.Author = &graphModel.User{
+			ID:       c.AuthorID,
+			Username: c.Username,
+		}
+	}
+
+	return comment
+}
+
+// toGraphComments преобразует список комментариев в GraphQL-модель
+func toGraphComments(comments []*commonModel.Comment) []*graphModel.Comment {
+	result := make([]*graphModel.Comment, 0, len(comments))
+	for _, c := range comments {
+		result = append(result, toGraphComment(c))
+	}
+	return result
+}
+
+// toGraphPost преобразует пост из доменной модели в GraphQL-модель
+func toGraphPost(p *commonModel.Post) *graphModel.Post {
+	return &graphModel.Post{
+		ID:            p.ID,
+		Title:         p.Title,
+		Content:       p.Content,
+		AllowComments: p.AllowComments,
+		AuthorID:      p.AuthorID,
+		HaveComments:  p.CommentCount > 0,
+		CommentCount:  int32(p.CommentCount),
+		Score:         int32(p.Score),
+		Upvotes:       int32(p.Upvotes),
+		Downvotes:     int32(p.Downvotes),
+		CreatedAt:     p.CreatedAt,
+		EditedAt:      p.EditedAt,
+		DeletedAt:     p.DeletedAt,
+		ForumID:       p.ForumID,
+		Tags:          append([]string{}, p.Tags...),
+	}
+}
+
+// toPostFilter собирает фильтр ленты из аргументов запроса
+func toPostFilter(forum *string, tags []string) commonModel.PostFilter {
+	filter := commonModel.PostFilter{Tags: commonModel.NormalizeTags(tags)}
+	if forum != nil {
+		filter.ForumID = *forum
+	}
+	return filter
+}
+
+// toGraphForum преобразует раздел форума
+func toGraphForum(f *commonModel.Forum) *graphModel.Forum {
+	return &graphModel.Forum{
+		ID:          f.ID,
+		Name:        f.Name,
+		Description: f.Description,
+		Position:    int32(f.Position),
+		CreatedAt:   f.CreatedAt,
+	}
+}
+
+func toGraphForums(forums []*commonModel.Forum) []*graphModel.Forum {
+	result := make([]*graphModel.Forum, len(forums))
+	for i, f := range forums {
+		result[i] = toGraphForum(f)
+	}
+	return result
+}
+
+func toGraphTags(tags []*commonModel.Tag) []*graphModel.Tag {
+	result := make([]*graphModel.Tag, len(tags))
+	for i, t := range tags {
+		result[i] = &graphModel.Tag{Name: t.Name, PostCount: int32(t.PostCount)}
+	}
+	return result
+}
+
+// toGraphPostConnection преобразует страницу постов в Relay-соединение
+func toGraphPostConnection(page *commonModel.Page[*commonModel.Post], order commonModel.SortOrder, after *commonModel.Cursor) *graphModel.PostConnection {
+	edges := make([]*graphModel.PostEdge, 0, len(page.Items))
+	for _, p := range page.Items {
+		edges = append(edges, &graphModel.PostEdge{
+			Cursor: order.PostCursor(p).Encode(),
+			Node:   toGraphPost(p),
+		})
+	}
+
+	pageInfo := &graphModel.PageInfo{
+		HasNextPage:     page.HasNextPage,
+		HasPreviousPage: after != nil,
+	}
+	if len(edges) > 0 {
+		pageInfo.StartCursor = &edges[0].Cursor
+		pageInfo.EndCursor = &edges[len(edges)-1].Cursor
+	}
+
+	return &graphModel.PostConnection{
+		Edges:      edges,
+		PageInfo:   pageInfo,
+		TotalCount: int32(page.TotalCount),
+	}
+}
+
+// toGraphCommentConnection преобразует страницу комментариев в Relay-соединение
+// с курсорами порядка order
+func toGraphCommentConnection(page *commonModel.Page[*commonModel.Comment], order commonModel.SortOrder, after *commonModel.Cursor) *graphModel.CommentConnection {
+	return toGraphCommentEdges(page, after != nil, func(c *commonModel.Comment) string {
+		return order.CommentCursor(c).Encode()
+	})
+}
+
+// toSortOrder преобразует аргумент orderBy, nil – порядок def
+func toSortOrder(orderBy *graphModel.SortOrder, def commonModel.SortOrder) commonModel.SortOrder {
+	if orderBy == nil {
+		return def
+	}
+	return commonModel.SortOrder(*orderBy)
+}
+
+// toGraphThreadConnection преобразует страницу обхода ветки в Relay-соединение
+// с курсорами по материализованному пути
+func toGraphThreadConnection(page *commonModel.Page[*commonModel.Comment], hasPreviousPage bool) *graphModel.CommentConnection {
+	return toGraphCommentEdges(page, hasPreviousPage, func(c *commonModel.Comment) string {
+		return commonModel.EncodePathCursor(c.Path)
+	})
+}
+
+// toGraphCommentEdges строит соединение, вычисляя курсор рёбер функцией cursorOf
+func toGraphCommentEdges(page *commonModel.Page[*commonModel.Comment], hasPreviousPage bool, cursorOf func(*commonModel.Comment) string) *graphModel.CommentConnection {
+	edges := make([]*graphModel.CommentEdge, 0, len(page.Items))
+	for _, c := range page.Items {
+		edges = append(edges, &graphModel.CommentEdge{
+			Cursor: cursorOf(c),
+			Node:   toGraphComment(c),
+		})
+	}
+
+	pageInfo := &graphModel.PageInfo{
+		HasNextPage:     page.HasNextPage,
+		HasPreviousPage: hasPreviousPage,
+	}
+	if len(edges) > 0 {
+		pageInfo.StartCursor = &edges[0].Cursor
+		pageInfo.EndCursor = &edges[len(edges)-1].Cursor
+	}
+
+	return &graphModel.CommentConnection{
+		Edges:      edges,
+		PageInfo:   pageInfo,
+		TotalCount: int32(page.TotalCount),
+	}
+}
+
+// toGraphCommentTree преобразует дерево комментариев во вложенное
+// и плоское (обход в глубину) представления
+func toGraphCommentTree(t *commonModel.CommentTree) *graphModel.CommentTree {
+	tree := &graphModel.CommentTree{
+		Flat:           []*graphModel.CommentTreeNode{},
+		TruncatedRoots: int32(t.TruncatedRoots),
+	}
+	tree.Roots = toGraphCommentNodes(t.Roots, &tree.Flat)
+	return tree
+}
+
+// toGraphCommentNodes преобразует узлы и дописывает их в flat в порядке обхода в глубину
+func toGraphCommentNodes(nodes []*commonModel.CommentNode, flat *[]*graphModel.CommentTreeNode) []*graphModel.CommentTreeNode {
+	result := make([]*graphModel.CommentTreeNode, 0, len(nodes))
+	for _, n := range nodes {
+		node := &graphModel.CommentTreeNode{
+			Comment:          toGraphComment(n.Comment),
+			Depth:            int32(n.Depth),
+			TruncatedReplies: int32(n.TruncatedReplies),
+		}
+		*flat = append(*flat, node)
+		node.Children = toGraphCommentNodes(n.Children, flat)
+		result = append(result, node)
+	}
+	return result
+}
+
+// toGraphPresence преобразует состояние присутствия поста в GraphQL-модель.
+// Темой трекера присутствия служит ID поста.
+func toGraphPresence(s pubsub.PresenceSnapshot) *graphModel.PostPresence {
+	return &graphModel.PostPresence{
+		PostID:    s.Topic,
+		Count:     int32(s.Count),
+		Usernames: s.Usernames,
+	}
+}
+
+// toGraphTypingEvent преобразует событие набора текста в GraphQL-модель
+func toGraphTypingEvent(e pubsub.TypingEvent) *graphModel.TypingEvent {
+	event := &graphModel.TypingEvent{
+		PostID: e.PostID,
+		User: &graphModel.User{
+			ID:       e.Viewer.UserID,
+			Username: e.Viewer.Username,
+		},
+		Active: e.Active,
+	}
+	if e.ParentID != "" {
+		parentID := e.ParentID
+		event.ParentID = &parentID
+	}
+	return event
+}
+
+// toGraphRevisions преобразует ревизии в GraphQL-модель
+func toGraphRevisions(revisions []*commonModel.Revision) []*graphModel.Revision {
+	result := make([]*graphModel.Revision, 0, len(revisions))
+	for _, rev := range revisions {
+		result = append(result, &graphModel.Revision{
+			ID:        rev.ID,
+			Title:     rev.Title,
+			Content:   rev.Content,
+			EditorID:  rev.EditorID,
+			CreatedAt: rev.CreatedAt,
+		})
+	}
+	return result
+}
+
+// diffOps – соответствие видов строк разницы значениям enum DiffOp
+var diffOps = map[diff.Op]graphModel.DiffOp{
+	diff.Equal:  graphModel.DiffOpEqual,
+	diff.Insert: graphModel.DiffOpInsert,
+	diff.Delete: graphModel.DiffOpDelete,
+}
+
+// toGraphDiffLines преобразует построчную разницу в GraphQL-модель
+func toGraphDiffLines(lines []diff.Line) []*graphModel.DiffLine {
+	result := make([]*graphModel.DiffLine, 0, len(lines))
+	for _, l := range lines {
+		result = append(result, &graphModel.DiffLine{Op: diffOps[l.Op], Text: l.Text})
+	}
+	return result
+}
+
+// toGraphVotable преобразует запись, за которую проголосовали
+func toGraphVotable(v *commonModel.Votable) graphModel.Votable {
+	if v.Post != nil {
+		return toGraphPost(v.Post)
+	}
+	return toGraphComment(v.Comment)
+}
+
+// toGraphBookmarkNode преобразует запись из закладок
+func toGraphBookmarkNode(b *commonModel.Bookmark) graphModel.BookmarkNode {
+	if b.Post != nil {
+		return toGraphPost(b.Post)
+	}
+	return toGraphComment(b.Comment)
+}
+
+// toGraphBookmarkConnection преобразует страницу закладок в Relay-соединение.
+// Закладки на записи, удалённые во время запроса, пропускаются
+func toGraphBookmarkConnection(page *commonModel.Page[*commonModel.Bookmark], after *commonModel.Cursor) *graphModel.BookmarkConnection {
+	edges := make([]*graphModel.BookmarkEdge, 0, len(page.Items))
+	for _, b := range page.Items {
+		if b.Post == nil && b.Comment == nil {
+			continue
+		}
+		edges = append(edges, &graphModel.BookmarkEdge{
+			Cursor: b.Cursor().Encode(),
+			Node: &graphModel.Bookmark{
+				Node:      toGraphBookmarkNode(b),
+				CreatedAt: b.CreatedAt,
+			},
+		})
+	}
+
+	pageInfo := &graphModel.PageInfo{
+		HasNextPage:     page.HasNextPage,
+		HasPreviousPage: after != nil,
+	}
+	if len(edges) > 0 {
+		pageInfo.StartCursor = &edges[0].Cursor
+		pageInfo.EndCursor = &edges[len(edges)-1].Cursor
+	}
+
+	return &graphModel.BookmarkConnection{
+		Edges:      edges,
+		PageInfo:   pageInfo,
+		TotalCount: int32(page.TotalCount),
+	}
+}
+
+// toGraphReactions преобразует реакции на запись
+func toGraphReactions(counts []*commonModel.ReactionCount) []*graphModel.Reaction {
+	reactions := make([]*graphModel.Reaction, len(counts))
+	for i, c := range counts {
+		reactions[i] = &graphModel.Reaction{
+			Emoji:         c.Emoji,
+			Count:         int32(c.Count),
+			ViewerReacted: c.ViewerReacted,
+		}
+	}
+	return reactions
+}
+
+// toGraphReactionsChangedEvent собирает событие подписки reactionsChanged.
+// Отметка viewerReacted относится к автору изменения, поэтому в событие не попадает
+func toGraphReactionsChangedEvent(change *commonModel.ReactionChange, user *commonModel.User, emoji string, added bool) *graphModel.ReactionsChangedEvent {
+	reactions := make([]*graphModel.ReactionCount, len(change.Reactions))
+	for i, c := range change.Reactions {
+		reactions[i] = &graphModel.ReactionCount{Emoji: c.Emoji, Count: int32(c.Count)}
+	}
+
+	return &graphModel.ReactionsChangedEvent{
+		PostID:    change.PostID,
+		TargetID:  change.TargetID,
+		User:      &graphModel.User{ID: user.ID, Username: user.Username},
+		Emoji:     emoji,
+		Added:     added,
+		Reactions: reactions,
+	}
+}
+
+// snippetTags заменяет маркеры совпадений на теги HTML. Маркеры не
+// экранируются html.EscapeString, поэтому замена идёт после экранирования
+var snippetTags = strings.NewReplacer(search.MarkStart, "<b>", search.MarkStop, "</b>")
+
+// toGraphSearchConnection преобразует страницу результатов поиска в Relay-соединение
+func toGraphSearchConnection(page *commonModel.Page[*commonModel.SearchHit], after *commonModel.Cursor) *graphModel.SearchConnection {
+	edges := make([]*graphModel.SearchEdge, 0, len(page.Items))
+	for _, h := range page.Items {
+		result := &graphModel.SearchResult{
+			Rank:    h.Rank,
+			Snippet: snippetTags.Replace(html.EscapeString(h.Snippet)),
+		}
+		if h.Post != nil {
+			result.Node = toGraphPost(h.Post)
+		} else {
+			result.Node = toGraphComment(h.Comment)
+		}
+		edges = append(edges, &graphModel.SearchEdge{
+			Cursor: h.Cursor().Encode(),
+			Node:   result,
+		})
+	}
+
+	pageInfo := &graphModel.PageInfo{
+		HasNextPage:     page.HasNextPage,
+		HasPreviousPage: after != nil,
+	}
+	if len(edges) > 0 {
+		pageInfo.StartCursor = &edges[0].Cursor
+		pageInfo.EndCursor = &edges[len(edges)-1].Cursor
+	}
+
+	return &graphModel.SearchConnection{
+		Edges:      edges,
+		PageInfo:   pageInfo,
+		TotalCount: int32(page.TotalCount),
+	}
+}
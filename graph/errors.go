@@ -0,0 +1,75 @@
+package graph
+
+import (
+	"context"
+	"errors"
+
+	"github.com/22Fariz22/forum/internal/repository"
+	"github.com/22Fariz22/forum/internal/validation"
+	"github.com/22Fariz22/forum/pkg/logger"
+	"github.com/99designs/gqlgen/graphql"
+	"github.com/vektah/gqlparser/v2/gqlerror"
+)
+
+// Коды extensions.code для ошибок хранилища
+var repositoryErrorCodes = []struct {
+	kind error
+	code string
+}{
+	{repository.ErrNotFound, "404"},
+	{repository.ErrConflict, "409"},
+	{repository.ErrForbidden, "403"},
+	{repository.ErrValidation, "400"},
+	{repository.ErrUnavailable, "503"},
+}
+
+// NewErrorPresenter переводит ошибки резолверов в ответ с устойчивым extensions.code.
+// Ошибки, уже собранные через utils.NewGraphQLError, и ошибки разбора запроса
+// возвращаются как есть. Ошибки validation.Errors получают код 400 и
+// extensions.fields. Неизвестные ошибки логируются, а клиент получает код 500
+// без внутренних подробностей.
+func NewErrorPresenter(logger logger.Logger) graphql.ErrorPresenterFunc {
+	return func(ctx context.Context, err error) *gqlerror.Error {
+		// gqlgen оборачивает ошибки резолверов в gqlerror.Error с путём поля,
+		// поэтому готовой считается только ошибка с кодом или без вложенной ошибки
+		gqlErr := graphql.DefaultErrorPresenter(ctx, err)
+		if _, ok := gqlErr.Extensions["code"]; ok || gqlErr.Err == nil {
+			return gqlErr
+		}
+
+		message, code := "ошибка на сервере", "500"
+		extensions := map[string]interface{}{}
+
+		var fieldErrs validation.Errors
+		switch {
+		case errors.As(err, &fieldErrs):
+			// Сообщения по полям: имя аргумента мутации → причина
+			message, code = "некорректные данные", "400"
+			extensions["fields"] = map[string]string(fieldErrs)
+		case errors.Is(err, context.DeadlineExceeded):
+			message, code = "превышено время ожидания", "504"
+		default:
+			for _, c := range repositoryErrorCodes {
+				if errors.Is(err, c.kind) {
+					message, _ = repository.Message(err)
+					code = c.code
+					break
+				}
+			}
+		}
+
+		if code == "500" || code == "503" {
+			logger.Errorf("ошибка резолвера %v: %v", gqlErr.Path, gqlErr.Err)
+		}
+
+		extensions["code"] = code
+
+		return &gqlerror.Error{
+			Err:        gqlErr.Err,
+			Message:    message,
+			Path:       gqlErr.Path,
+			Locations:  gqlErr.Locations,
+			Extensions: extensions,
+		}
+	}
+}
@@ -0,0 +1,236 @@
+package graph
+
+import (
+	"context"
+
+	graphModel "github.com/22Fariz22/forum/graph/model"
+	"github.com/22Fariz22/forum/internal/auth"
+	commonModel "github.com/22Fariz22/forum/internal/model"
+	"github.com/22Fariz22/forum/pkg/dataloader"
+	"github.com/99designs/gqlgen/graphql"
+)
+
+// pageKey – ключ загрузки страницы дочерних комментариев: ID поста
+// или родительского комментария, порядок и параметры пагинации
+type pageKey struct {
+	ID     string
+	Order  commonModel.SortOrder
+	Offset int
+	Limit  int
+}
+
+// viewerKey – ключ загрузки данных пользователя о посте или комментарии:
+// голоса, реакций или закладки. Пустой UserID – анонимный пользователь
+type viewerKey struct {
+	UserID   string
+	TargetID string
+}
+
+// Loaders – загрузчики одного GraphQL-запроса. Поля Post.comments и
+// Comment.replies на одном уровне вложенности собираются в один запрос к хранилищу,
+// как и viewerVote, reactions и viewerHasBookmarked всех постов и комментариев
+// и разделы постов.
+type Loaders struct {
+	Comments  *dataloader.Loader[pageKey, []*commonModel.Comment]
+	Replies   *dataloader.Loader[pageKey, []*commonModel.Comment]
+	Votes     *dataloader.Loader[viewerKey, int]
+	Reactions *dataloader.Loader[viewerKey, []*commonModel.ReactionCount]
+	Forums    *dataloader.Loader[string, *commonModel.Forum]
+	Bookmarks *dataloader.Loader[viewerKey, bool]
+}
+
+// NewLoaders создаёт загрузчики поверх хранилища
+func NewLoaders(repo Repository) *Loaders {
+	return &Loaders{
+		Comments:  dataloader.New(batchByPage(repo.GetCommentsByPostIDs), dataloader.Options{}),
+		Replies:   dataloader.New(batchByPage(repo.GetRepliesByParentIDs), dataloader.Options{}),
+		Votes:     dataloader.New(batchByViewer(repo.GetVotes), dataloader.Options{}),
+		Reactions: dataloader.New(batchByViewer(repo.GetReactions), dataloader.Options{}),
+		Forums:    dataloader.New(batchForums(repo), dataloader.Options{}),
+		Bookmarks: dataloader.New(batchByViewer(repo.GetBookmarked), dataloader.Options{}),
+	}
+}
+
+// batchByViewer группирует ключи по пользователю и загружает данные
+// пользователя о записях одним вызовом fetch на пользователя; записей без
+// данных нет в результате fetch
+func batchByViewer[V any](
+	fetch func(ctx context.Context, userID string, targetIDs []string) (map[string]V, error),
+) dataloader.BatchFunc[viewerKey, V] {
+	return func(ctx context.Context, keys []viewerKey) (map[viewerKey]V, error) {
+		groups := make(map[string][]string)
+		for _, k := range keys {
+			groups[k.UserID] = append(groups[k.UserID], k.TargetID)
+		}
+
+		result := make(map[viewerKey]V, len(keys))
+		for userID, targetIDs := range groups {
+			values, err := fetch(ctx, userID, targetIDs)
+			if err != nil {
+				return nil, err
+			}
+			for targetID, value := range values {
+				result[viewerKey{userID, targetID}] = value
+			}
+		}
+
+		return result, nil
+	}
+}
+
+// batchForums загружает разделы постов. Разделов немного, поэтому читается
+// весь список; ID без раздела нет в результате
+func batchForums(repo Repository) dataloader.BatchFunc[string, *commonModel.Forum] {
+	return func(ctx context.Context, ids []string) (map[string]*commonModel.Forum, error) {
+		forums, err := repo.GetForums(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		result := make(map[string]*commonModel.Forum, len(forums))
+		for _, f := range forums {
+			result[f.ID] = f
+		}
+
+		return result, nil
+	}
+}
+
+// batchByPage группирует ключи по порядку и параметрам пагинации: одинаковые
+// order/offset/limit загружаются одним вызовом fetch
+func batchByPage(
+	fetch func(ctx context.Context, ids []string, order commonModel.SortOrder, offset, limit int) (map[string][]*commonModel.Comment, error),
+) dataloader.BatchFunc[pageKey, []*commonModel.Comment] {
+	return func(ctx context.Context, keys []pageKey) (map[pageKey][]*commonModel.Comment, error) {
+		type page struct {
+			order         commonModel.SortOrder
+			offset, limit int
+		}
+
+		groups := make(map[page][]string)
+		for _, k := range keys {
+			p := page{k.Order, k.Offset, k.Limit}
+			groups[p] = append(groups[p], k.ID)
+		}
+
+		result := make(map[pageKey][]*commonModel.Comment, len(keys))
+		for p, ids := range groups {
+			comments, err := fetch(ctx, ids, p.order, p.offset, p.limit)
+			if err != nil {
+				return nil, err
+			}
+			for _, id := range ids {
+				result[pageKey{id, p.order, p.offset, p.limit}] = comments[id]
+			}
+		}
+
+		return result, nil
+	}
+}
+
+type loadersKey struct{}
+
+// LoadersMiddleware создаёт новые загрузчики для каждой операции
+func LoadersMiddleware(repo Repository) graphql.OperationMiddleware {
+	return func(ctx context.Context, next graphql.OperationHandler) graphql.ResponseHandler {
+		return next(context.WithValue(ctx, loadersKey{}, NewLoaders(repo)))
+	}
+}
+
+// loaders возвращает загрузчики операции. Без LoadersMiddleware
+// создаются отдельные загрузчики, которые просто не объединяют запросы.
+func (r *Resolver) loaders(ctx context.Context) *Loaders {
+	if l, ok := ctx.Value(loadersKey{}).(*Loaders); ok {
+		return l
+	}
+	return NewLoaders(r.Repo)
+}
+
+// childPageArgs возвращает offset и limit для списка дочерних комментариев.
+// Явные аргументы поля важнее устаревших offset/limit запроса post(id, offset, limit).
+func childPageArgs(ctx context.Context, limit, offset *int32) (int, int) {
+	l, o := defaultPageSize, 0
+	if limit != nil {
+		l = int(*limit)
+	}
+	if offset != nil {
+		o = int(*offset)
+	}
+
+	fc := graphql.GetFieldContext(ctx)
+	if fc == nil || fc.Parent == nil || fc.Parent.Field.Field == nil {
+		return l, o
+	}
+	if fc.Field.Arguments.ForName("limit") != nil || fc.Field.Arguments.ForName("offset") != nil {
+		return l, o
+	}
+
+	parent := fc.Parent
+	if parent.Object != "Query" || parent.Field.Name != "post" {
+		return l, o
+	}
+	if parent.Field.Arguments.ForName("limit") != nil {
+		if v, ok := parent.Args["limit"].(int32); ok {
+			l = int(v)
+		}
+	}
+	if parent.Field.Arguments.ForName("offset") != nil {
+		if v, ok := parent.Args["offset"].(int32); ok {
+			o = int(v)
+		}
+	}
+
+	return l, o
+}
+
+// loadComments загружает страницу дочерних комментариев через загрузчик
+func loadComments(ctx context.Context, loader *dataloader.Loader[pageKey, []*commonModel.Comment], id string, limit, offset *int32, order commonModel.SortOrder) ([]*graphModel.Comment, error) {
+	l, o := childPageArgs(ctx, limit, offset)
+
+	comments, err := loader.Load(ctx, pageKey{ID: id, Order: order, Offset: o, Limit: l})
+	if err != nil {
+		return nil, err
+	}
+
+	return toGraphComments(comments), nil
+}
+
+// viewerVote возвращает голос текущего пользователя за запись или nil,
+// если пользователь не представился
+func (r *Resolver) viewerVote(ctx context.Context, targetID string) (*int32, error) {
+	userID, ok := auth.UserID(ctx)
+	if !ok {
+		return nil, nil
+	}
+
+	value, err := r.loaders(ctx).Votes.Load(ctx, viewerKey{UserID: userID, TargetID: targetID})
+	if err != nil {
+		return nil, err
+	}
+
+	vote := int32(value)
+	return &vote, nil
+}
+
+// reactions возвращает реакции на запись с отметками текущего пользователя
+func (r *Resolver) reactions(ctx context.Context, targetID string) ([]*graphModel.Reaction, error) {
+	userID, _ := auth.UserID(ctx)
+
+	counts, err := r.loaders(ctx).Reactions.Load(ctx, viewerKey{UserID: userID, TargetID: targetID})
+	if err != nil {
+		return nil, err
+	}
+
+	return toGraphReactions(counts), nil
+}
+
+// viewerHasBookmarked сообщает, сохранена ли запись в закладки текущего
+// пользователя; false, если пользователь не представился
+func (r *Resolver) viewerHasBookmarked(ctx context.Context, targetID string) (bool, error) {
+	userID, ok := auth.UserID(ctx)
+	if !ok {
+		return false, nil
+	}
+
+	return r.loaders(ctx).Bookmarks.Load(ctx, viewerKey{UserID: userID, TargetID: targetID})
+}
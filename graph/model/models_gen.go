@@ -3,41 +3,375 @@
 package model
 
 import (
+	"fmt"
+	"io"
+	"strconv"
 	"time"
 )
 
+type BookmarkNode interface {
+	IsBookmarkNode()
+}
+
+type SearchNode interface {
+	IsSearchNode()
+}
+
+type Votable interface {
+	IsVotable()
+}
+
+type Bookmark struct {
+	Node      BookmarkNode `json:"node"`
+	CreatedAt time.Time    `json:"createdAt"`
+}
+
+type BookmarkConnection struct {
+	Edges      []*BookmarkEdge `json:"edges"`
+	PageInfo   *PageInfo       `json:"pageInfo"`
+	TotalCount int32           `json:"totalCount"`
+}
+
+type BookmarkEdge struct {
+	Cursor string    `json:"cursor"`
+	Node   *Bookmark `json:"node"`
+}
+
 type Comment struct {
-	ID           string    `json:"id"`
-	PostID       string    `json:"postID"`
-	ParentID     *string   `json:"parentID,omitempty"`
-	Content      string    `json:"content"`
-	Author       *User     `json:"author"`
-	CreatedAt    time.Time `json:"createdAt"`
-	HaveComments bool      `json:"haveComments"`
+	ID                  string        `json:"id"`
+	PostID              string        `json:"postID"`
+	ParentID            *string       `json:"parentID,omitempty"`
+	Content             string        `json:"content"`
+	Author              *User         `json:"author"`
+	CreatedAt           time.Time     `json:"createdAt"`
+	HaveComments        bool          `json:"haveComments"`
+	ReplyCount          int32         `json:"replyCount"`
+	Score               int32         `json:"score"`
+	Upvotes             int32         `json:"upvotes"`
+	Downvotes           int32         `json:"downvotes"`
+	ViewerVote          *int32        `json:"viewerVote,omitempty"`
+	Reactions           []*Reaction   `json:"reactions"`
+	ViewerHasBookmarked bool          `json:"viewerHasBookmarked"`
+	Cursor              string        `json:"cursor"`
+	EditCursor          *string       `json:"editCursor,omitempty"`
+	Replies             []*Comment    `json:"replies"`
+	Depth               int32         `json:"depth"`
+	EditedAt            *time.Time    `json:"editedAt,omitempty"`
+	DeletedAt           *time.Time    `json:"deletedAt,omitempty"`
+	Revisions           []*Revision   `json:"revisions,omitempty"`
+	Diff                *RevisionDiff `json:"diff,omitempty"`
+}
+
+func (Comment) IsBookmarkNode() {}
+
+func (Comment) IsSearchNode() {}
+
+func (Comment) IsVotable() {}
+
+type CommentConnection struct {
+	Edges      []*CommentEdge `json:"edges"`
+	PageInfo   *PageInfo      `json:"pageInfo"`
+	TotalCount int32          `json:"totalCount"`
+}
+
+type CommentEdge struct {
+	Cursor string   `json:"cursor"`
+	Node   *Comment `json:"node"`
+}
+
+type CommentTree struct {
+	Roots          []*CommentTreeNode `json:"roots"`
+	Flat           []*CommentTreeNode `json:"flat"`
+	TruncatedRoots int32              `json:"truncatedRoots"`
+}
+
+type CommentTreeNode struct {
+	Comment          *Comment           `json:"comment"`
+	Depth            int32              `json:"depth"`
+	TruncatedReplies int32              `json:"truncatedReplies"`
+	Children         []*CommentTreeNode `json:"children"`
+}
+
+type DiffLine struct {
+	Op   DiffOp `json:"op"`
+	Text string `json:"text"`
+}
+
+type Forum struct {
+	ID          string    `json:"id"`
+	Name        string    `json:"name"`
+	Description string    `json:"description"`
+	Position    int32     `json:"position"`
+	CreatedAt   time.Time `json:"createdAt"`
 }
 
 type Mutation struct {
 }
 
+type PageInfo struct {
+	HasNextPage     bool    `json:"hasNextPage"`
+	HasPreviousPage bool    `json:"hasPreviousPage"`
+	StartCursor     *string `json:"startCursor,omitempty"`
+	EndCursor       *string `json:"endCursor,omitempty"`
+}
+
 type Post struct {
-	ID            string     `json:"id"`
-	Title         string     `json:"title"`
-	Content       string     `json:"content"`
-	AllowComments bool       `json:"allowComments"`
-	AuthorID      string     `json:"authorID"`
-	HaveComments  bool       `json:"haveComments"`
-	CreatedAt     time.Time  `json:"createdAt"`
-	Comments      []*Comment `json:"comments"`
+	ID                  string             `json:"id"`
+	Title               string             `json:"title"`
+	Content             string             `json:"content"`
+	AllowComments       bool               `json:"allowComments"`
+	AuthorID            string             `json:"authorID"`
+	HaveComments        bool               `json:"haveComments"`
+	CommentCount        int32              `json:"commentCount"`
+	Score               int32              `json:"score"`
+	Upvotes             int32              `json:"upvotes"`
+	Downvotes           int32              `json:"downvotes"`
+	ViewerVote          *int32             `json:"viewerVote,omitempty"`
+	Reactions           []*Reaction        `json:"reactions"`
+	ViewerHasBookmarked bool               `json:"viewerHasBookmarked"`
+	ForumID             *string            `json:"forumID,omitempty"`
+	Forum               *Forum             `json:"forum,omitempty"`
+	Tags                []string           `json:"tags"`
+	CreatedAt           time.Time          `json:"createdAt"`
+	EditedAt            *time.Time         `json:"editedAt,omitempty"`
+	DeletedAt           *time.Time         `json:"deletedAt,omitempty"`
+	Comments            []*Comment         `json:"comments"`
+	CommentsConnection  *CommentConnection `json:"commentsConnection"`
+	Revisions           []*Revision        `json:"revisions,omitempty"`
+	Diff                *RevisionDiff      `json:"diff,omitempty"`
+}
+
+func (Post) IsBookmarkNode() {}
+
+func (Post) IsSearchNode() {}
+
+func (Post) IsVotable() {}
+
+type PostConnection struct {
+	Edges      []*PostEdge `json:"edges"`
+	PageInfo   *PageInfo   `json:"pageInfo"`
+	TotalCount int32       `json:"totalCount"`
+}
+
+type PostEdge struct {
+	Cursor string `json:"cursor"`
+	Node   *Post  `json:"node"`
+}
+
+type PostPresence struct {
+	PostID    string   `json:"postID"`
+	Count     int32    `json:"count"`
+	Usernames []string `json:"usernames"`
 }
 
 type Query struct {
 }
 
+type Reaction struct {
+	Emoji         string `json:"emoji"`
+	Count         int32  `json:"count"`
+	ViewerReacted bool   `json:"viewerReacted"`
+}
+
+type ReactionCount struct {
+	Emoji string `json:"emoji"`
+	Count int32  `json:"count"`
+}
+
+type ReactionsChangedEvent struct {
+	PostID    string           `json:"postID"`
+	TargetID  string           `json:"targetID"`
+	User      *User            `json:"user"`
+	Emoji     string           `json:"emoji"`
+	Added     bool             `json:"added"`
+	Reactions []*ReactionCount `json:"reactions"`
+}
+
+type Revision struct {
+	ID        string    `json:"id"`
+	Title     *string   `json:"title,omitempty"`
+	Content   string    `json:"content"`
+	EditorID  string    `json:"editorID"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+type RevisionDiff struct {
+	Title   []*DiffLine `json:"title,omitempty"`
+	Content []*DiffLine `json:"content"`
+}
+
+type SearchConnection struct {
+	Edges      []*SearchEdge `json:"edges"`
+	PageInfo   *PageInfo     `json:"pageInfo"`
+	TotalCount int32         `json:"totalCount"`
+}
+
+type SearchEdge struct {
+	Cursor string        `json:"cursor"`
+	Node   *SearchResult `json:"node"`
+}
+
+type SearchResult struct {
+	Node    SearchNode `json:"node"`
+	Rank    float64    `json:"rank"`
+	Snippet string     `json:"snippet"`
+}
+
 type Subscription struct {
 }
 
+type Tag struct {
+	Name      string `json:"name"`
+	PostCount int32  `json:"postCount"`
+}
+
+type TypingEvent struct {
+	PostID   string  `json:"postID"`
+	ParentID *string `json:"parentID,omitempty"`
+	User     *User   `json:"user"`
+	Active   bool    `json:"active"`
+}
+
 type User struct {
 	ID        string    `json:"id"`
 	Username  string    `json:"username"`
 	CreatedAt time.Time `json:"createdAt"`
 }
+
+type Viewer struct {
+	User      *User               `json:"user"`
+	Bookmarks *BookmarkConnection `json:"bookmarks"`
+}
+
+type DiffOp string
+
+const (
+	DiffOpEqual  DiffOp = "EQUAL"
+	DiffOpInsert DiffOp = "INSERT"
+	DiffOpDelete DiffOp = "DELETE"
+)
+
+var AllDiffOp = []DiffOp{
+	DiffOpEqual,
+	DiffOpInsert,
+	DiffOpDelete,
+}
+
+func (e DiffOp) IsValid() bool {
+	switch e {
+	case DiffOpEqual, DiffOpInsert, DiffOpDelete:
+		return true
+	}
+	return false
+}
+
+func (e DiffOp) String() string {
+	return string(e)
+}
+
+func (e *DiffOp) UnmarshalGQL(v any) error {
+	str, ok := v.(string)
+	if !ok {
+		return fmt.Errorf("enums must be strings")
+	}
+
+	*e = DiffOp(str)
+	if !e.IsValid() {
+		return fmt.Errorf("%s is not a valid DiffOp", str)
+	}
+	return nil
+}
+
+func (e DiffOp) MarshalGQL(w io.Writer) {
+	fmt.Fprint(w, strconv.Quote(e.String()))
+}
+
+type SearchKind string
+
+const (
+	SearchKindAll     SearchKind = "ALL"
+	SearchKindPost    SearchKind = "POST"
+	SearchKindComment SearchKind = "COMMENT"
+)
+
+var AllSearchKind = []SearchKind{
+	SearchKindAll,
+	SearchKindPost,
+	SearchKindComment,
+}
+
+func (e SearchKind) IsValid() bool {
+	switch e {
+	case SearchKindAll, SearchKindPost, SearchKindComment:
+		return true
+	}
+	return false
+}
+
+func (e SearchKind) String() string {
+	return string(e)
+}
+
+func (e *SearchKind) UnmarshalGQL(v any) error {
+	str, ok := v.(string)
+	if !ok {
+		return fmt.Errorf("enums must be strings")
+	}
+
+	*e = SearchKind(str)
+	if !e.IsValid() {
+		return fmt.Errorf("%s is not a valid SearchKind", str)
+	}
+	return nil
+}
+
+func (e SearchKind) MarshalGQL(w io.Writer) {
+	fmt.Fprint(w, strconv.Quote(e.String()))
+}
+
+type SortOrder string
+
+const (
+	SortOrderNewest      SortOrder = "NEWEST"
+	SortOrderOldest      SortOrder = "OLDEST"
+	SortOrderTop         SortOrder = "TOP"
+	SortOrderHot         SortOrder = "HOT"
+	SortOrderMostReplied SortOrder = "MOST_REPLIED"
+)
+
+var AllSortOrder = []SortOrder{
+	SortOrderNewest,
+	SortOrderOldest,
+	SortOrderTop,
+	SortOrderHot,
+	SortOrderMostReplied,
+}
+
+func (e SortOrder) IsValid() bool {
+	switch e {
+	case SortOrderNewest, SortOrderOldest, SortOrderTop, SortOrderHot, SortOrderMostReplied:
+		return true
+	}
+	return false
+}
+
+func (e SortOrder) String() string {
+	return string(e)
+}
+
+func (e *SortOrder) UnmarshalGQL(v any) error {
+	str, ok := v.(string)
+	if !ok {
+		return fmt.Errorf("enums must be strings")
+	}
+
+	*e = SortOrder(str)
+	if !e.IsValid() {
+		return fmt.Errorf("%s is not a valid SortOrder", str)
+	}
+	return nil
+}
+
+func (e SortOrder) MarshalGQL(w io.Writer) {
+	fmt.Fprint(w, strconv.Quote(e.String()))
+}
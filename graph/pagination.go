@@ -0,0 +1,66 @@
+package graph
+
+import (
+	"fmt"
+
+	commonModel "github.com/22Fariz22/forum/internal/model"
+	"github.com/22Fariz22/forum/utils"
+)
+
+const (
+	// defaultPageSize – размер страницы, если first не указан
+	defaultPageSize = 10
+	// maxPageSize – наибольший допустимый first
+	maxPageSize = 100
+	// maxTreeDepth – наибольшая глубина дерева комментариев за один запрос
+	maxTreeDepth = 10
+)
+
+// pageSize проверяет аргумент first Relay-пагинации
+func pageSize(first *int32) (int, error) {
+	if first == nil {
+		return defaultPageSize, nil
+	}
+	if *first < 0 || *first > maxPageSize {
+		return 0, utils.NewGraphQLError(fmt.Sprintf("first должен быть от 0 до %d", maxPageSize), "400")
+	}
+	return int(*first), nil
+}
+
+// pageArgs проверяет аргументы Relay-пагинации и разбирает курсор
+func pageArgs(first *int32, after *string) (int, *commonModel.Cursor, error) {
+	size, err := pageSize(first)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	if after == nil {
+		return size, nil, nil
+	}
+
+	cursor, err := commonModel.DecodeCursor(*after)
+	if err != nil {
+		return 0, nil, utils.NewGraphQLError("некорректный курсор", "400")
+	}
+
+	return size, &cursor, nil
+}
+
+// threadPageArgs проверяет аргументы обхода ветки и разбирает курсор-путь
+func threadPageArgs(first *int32, after *string) (int, string, error) {
+	size, err := pageSize(first)
+	if err != nil {
+		return 0, "", err
+	}
+
+	if after == nil {
+		return size, "", nil
+	}
+
+	path, err := commonModel.DecodePathCursor(*after)
+	if err != nil {
+		return 0, "", utils.NewGraphQLError("некорректный курсор", "400")
+	}
+
+	return size, path, nil
+}
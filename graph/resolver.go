@@ -1,22 +1,28 @@
 package graph
 
 import (
+	"github.com/22Fariz22/forum/internal/auth"
 	"github.com/22Fariz22/forum/internal/repository"
-	"github.com/22Fariz22/forum/pubsub"
+	"github.com/22Fariz22/forum/internal/validation"
 )
 
 // Repository определён в пакете repository
 type Repository = repository.Repository
 
-// Resolver содержит ссылки на хранилище и систему pubsub для подписок.
+// Resolver содержит ссылки на хранилище, систему pubsub для подписок, роли пользователей
+// и валидатор ввода, через который проходят все мутации.
 type Resolver struct {
-	Repo   Repository
-	PubSub *pubsub.PubSub
+	Repo      Repository
+	PubSub    *Brokers
+	Roles     *auth.Roles
+	Validator *validation.Validator
 }
 
-func NewResolver(repo Repository) *Resolver {
+func NewResolver(repo Repository, brokers *Brokers, roles *auth.Roles, validator *validation.Validator) *Resolver {
 	return &Resolver{
-		Repo:   repo,
-		PubSub: pubsub.NewPubSub(),
+		Repo:      repo,
+		PubSub:    brokers,
+		Roles:     roles,
+		Validator: validator,
 	}
 }
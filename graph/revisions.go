@@ -0,0 +1,66 @@
+package graph
+
+import (
+	"context"
+
+	graphModel "github.com/22Fariz22/forum/graph/model"
+	commonModel "github.com/22Fariz22/forum/internal/model"
+	"github.com/22Fariz22/forum/pkg/diff"
+	"github.com/22Fariz22/forum/utils"
+)
+
+// revisions возвращает прежние версии поста или комментария автору и модераторам
+func (r *Resolver) revisions(ctx context.Context, entityID, authorID string) ([]*graphModel.Revision, error) {
+	if _, err := r.requireAuthorOrModerator(ctx, authorID); err != nil {
+		return nil, err
+	}
+
+	revisions, err := r.Repo.GetRevisions(ctx, entityID)
+	if err != nil {
+		return nil, err
+	}
+
+	return toGraphRevisions(revisions), nil
+}
+
+// revisionDiff сравнивает ревизию revisionA с ревизией revisionB или, если она
+// не указана, с текущей версией current. Обе ревизии должны относиться к entityID.
+func (r *Resolver) revisionDiff(ctx context.Context, entityID, authorID string, revisionA string, revisionB *string, current *commonModel.Revision) (*graphModel.RevisionDiff, error) {
+	if _, err := r.requireAuthorOrModerator(ctx, authorID); err != nil {
+		return nil, err
+	}
+
+	a, err := r.entityRevision(ctx, entityID, revisionA)
+	if err != nil {
+		return nil, err
+	}
+
+	b := current
+	if revisionB != nil {
+		if b, err = r.entityRevision(ctx, entityID, *revisionB); err != nil {
+			return nil, err
+		}
+	}
+
+	result := &graphModel.RevisionDiff{
+		Content: toGraphDiffLines(diff.Lines(a.Content, b.Content)),
+	}
+	if a.Title != nil && b.Title != nil {
+		result.Title = toGraphDiffLines(diff.Lines(*a.Title, *b.Title))
+	}
+
+	return result, nil
+}
+
+// entityRevision загружает ревизию и проверяет, что она относится к entityID
+func (r *Resolver) entityRevision(ctx context.Context, entityID, id string) (*commonModel.Revision, error) {
+	rev, err := r.Repo.GetRevisionByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if rev.EntityID != entityID {
+		return nil, utils.NewGraphQLError("ревизия не найдена", "404")
+	}
+
+	return rev, nil
+}
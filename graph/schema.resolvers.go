@@ -6,20 +6,75 @@ package graph
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"strings"
 
 	graphModel "github.com/22Fariz22/forum/graph/model"
 	commonModel "github.com/22Fariz22/forum/internal/model"
+	"github.com/22Fariz22/forum/internal/repository"
+	"github.com/22Fariz22/forum/internal/validation"
+	"github.com/22Fariz22/forum/pubsub"
 	"github.com/22Fariz22/forum/utils"
 	"github.com/google/uuid"
 )
 
+// ViewerVote загружает голос текущего пользователя пакетом для всех комментариев запроса
+func (r *commentResolver) ViewerVote(ctx context.Context, obj *graphModel.Comment) (*int32, error) {
+	return r.viewerVote(ctx, obj.ID)
+}
+
+// Reactions загружает реакции пакетом для всех комментариев запроса
+func (r *commentResolver) Reactions(ctx context.Context, obj *graphModel.Comment) ([]*graphModel.Reaction, error) {
+	return r.reactions(ctx, obj.ID)
+}
+
+// ViewerHasBookmarked загружает отметку закладки пакетом для всех комментариев запроса
+func (r *commentResolver) ViewerHasBookmarked(ctx context.Context, obj *graphModel.Comment) (bool, error) {
+	return r.viewerHasBookmarked(ctx, obj.ID)
+}
+
+// Replies загружает ответы на комментарий пакетом вместе с соседними комментариями
+func (r *commentResolver) Replies(ctx context.Context, obj *graphModel.Comment, limit *int32, offset *int32, orderBy *graphModel.SortOrder) ([]*graphModel.Comment, error) {
+	return loadComments(ctx, r.loaders(ctx).Replies, obj.ID, limit, offset, toSortOrder(orderBy, commonModel.OrderOldest))
+}
+
+// Revisions возвращает прежние версии комментария автору и модераторам
+func (r *commentResolver) Revisions(ctx context.Context, obj *graphModel.Comment) ([]*graphModel.Revision, error) {
+	return r.revisions(ctx, obj.ID, obj.Author.ID)
+}
+
+// Diff сравнивает ревизии комментария
+func (r *commentResolver) Diff(ctx context.Context, obj *graphModel.Comment, revisionA string, revisionB *string) (*graphModel.RevisionDiff, error) {
+	current := &commonModel.Revision{Content: obj.Content}
+	return r.revisionDiff(ctx, obj.ID, obj.Author.ID, revisionA, revisionB, current)
+}
+
 // CreatePost is the resolver for the createPost field.
-func (r *mutationResolver) CreatePost(ctx context.Context, title string, content string, allowComments bool, author string) (*graphModel.Post, error) {
-	// Проверяем, существует ли пользователь с таким ID
-	_, err := r.Repo.GetUserByID(author)
+func (r *mutationResolver) CreatePost(ctx context.Context, title string, content string, allowComments bool, author *string, forumID *string, tags []string) (*graphModel.Post, error) {
+	err := r.Validator.Validate(
+		validation.Value("title", validation.PostTitle, title),
+		validation.Value("content", validation.PostContent, content),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	tags = commonModel.NormalizeTags(tags)
+	if err := r.Validator.ValidateTags("tags", tags); err != nil {
+		return nil, err
+	}
+
+	user, err := r.requireAuthor(ctx, author)
 	if err != nil {
-		return nil, utils.NewGraphQLError("пользователь не найден", "404")
+		return nil, err
+	}
+
+	// Разделы не удаляются, поэтому достаточно проверить раздел перед вставкой
+	if forumID != nil {
+		if _, err := r.Repo.GetForumByID(ctx, *forumID); err != nil {
+			return nil, err
+		}
 	}
 
 	newPost := &commonModel.Post{
@@ -27,74 +82,75 @@ func (r *mutationResolver) CreatePost(ctx context.Context, title string, content
 		Title:         title,
 		Content:       content,
 		AllowComments: allowComments,
-		AuthorID:      author,
-	}
-
-	newPostQLModel := &graphModel.Post{
-		ID:            newPost.ID,
-		Title:         newPost.Title,
-		Content:       newPost.Content,
-		AllowComments: newPost.AllowComments,
-		AuthorID:      author,
+		AuthorID:      user.ID,
+		ForumID:       forumID,
+		Tags:          tags,
 	}
 
 	//сохраняем в базе
-	err = r.Repo.CreatePost(newPost)
+	err = r.Repo.CreatePost(ctx, newPost)
 	if err != nil {
-		return nil, utils.NewGraphQLError("ошибка на сервере", "500")
+		return nil, err
 	}
 
+	newPostQLModel := toGraphPost(newPost)
+
+	// Уведомляем живую ленту о новом посте
+	r.PubSub.Posts.Publish(postCreatedTopic, newPostQLModel)
+
 	return newPostQLModel, nil
 }
 
 // CreateCommentOnPost создаёт комментарий к посту
-func (r *mutationResolver) CreateCommentOnPost(ctx context.Context, postID string, content string, author string) (*graphModel.Comment, error) {
-	// Проверяем, существует ли пользователь с таким ID
-	user, err := r.Repo.GetUserByID(author)
-	if err != nil {
-		fmt.Println("err:", err)
-		return nil, utils.NewGraphQLError("пользователь не найден", "404")
+func (r *mutationResolver) CreateCommentOnPost(ctx context.Context, postID string, content string, author *string) (*graphModel.Comment, error) {
+	if err := r.Validator.Validate(validation.Value("content", validation.CommentContent, content)); err != nil {
+		return nil, err
+	}
 
+	user, err := r.requireAuthor(ctx, author)
+	if err != nil {
+		return nil, err
 	}
 
 	// Проверяем, существует ли пост
-	_, err = r.Repo.GetPostByID(postID)
+	_, err = r.Repo.GetPostByID(ctx, postID)
 	if err != nil {
-		fmt.Println("err: ", err)
 		return nil, err
 	}
 
 	// Создаём комментарий
 	comment := &commonModel.Comment{
-		ID:      uuid.New().String(),
-		PostID:  postID,
-		Content: content,
-		Author:  user,
+		ID:       uuid.New().String(),
+		PostID:   postID,
+		Content:  content,
+		Author:   user,
+		AuthorID: user.ID,
+		Username: user.Username,
 	}
 
 	// Добавляем комментарий
-	c, err := r.Repo.CreateCommentOnPost(context.Background(), comment)
+	c, err := r.Repo.CreateCommentOnPost(ctx, comment)
 	if err != nil {
-		fmt.Println("err in resolver call on r.Repo.CreateCommentOnPost: ", err)
-		return nil, utils.NewGraphQLError("ошибка на сервере", "500")
+		return nil, err
 	}
 
-	return &graphModel.Comment{
-		ID:        c.ID,
-		PostID:    c.PostID,
-		Content:   c.Content,
-		Author:    (*graphModel.User)(c.Author),
-		CreatedAt: c.CreatedAt,
-	}, nil
+	gqlComment := toGraphComment(c)
+
+	// Уведомляем подписчиков поста о новом комментарии
+	r.publishCommentAdded(gqlComment)
+
+	return gqlComment, nil
 }
 
 // ReplyToComment создаёт ответ на комментарий
-func (r *mutationResolver) ReplyToComment(ctx context.Context, postID string, parentID string, content string, author string) (*graphModel.Comment, error) {
-	// Проверяем, существует ли пользователь с таким ID
-	user, err := r.Repo.GetUserByID(author)
+func (r *mutationResolver) ReplyToComment(ctx context.Context, postID string, parentID string, content string, author *string) (*graphModel.Comment, error) {
+	if err := r.Validator.Validate(validation.Value("content", validation.CommentContent, content)); err != nil {
+		return nil, err
+	}
+
+	user, err := r.requireAuthor(ctx, author)
 	if err != nil {
-		fmt.Println("err:")
-		return nil, utils.NewGraphQLError("пользователь не найден", "404")
+		return nil, err
 	}
 
 	// Создаём вложенный комментарий
@@ -104,38 +160,38 @@ func (r *mutationResolver) ReplyToComment(ctx context.Context, postID string, pa
 		ParentID: &parentID,
 		Content:  content,
 		Author:   user,
+		AuthorID: user.ID,
+		Username: user.Username,
 	}
 
 	// Добавляем комментарий
-	c, err := r.Repo.ReplyToComment(context.Background(), comment)
+	c, err := r.Repo.ReplyToComment(ctx, comment)
 	if err != nil {
-		fmt.Println("err inr.Repo.ReplyToComment(context.Background(), comment) err:", err)
-		return nil, utils.NewGraphQLError("родительский коментарий не найден", "404")
+		return nil, err
 	}
 
-	return &graphModel.Comment{
-		ID:      c.ID,
-		PostID:  c.PostID,
-		Content: c.Content,
-		Author:  (*graphModel.User)(c.Author),
-	}, nil
+	gqlComment := toGraphComment(c)
+
+	// Ответы попадают и в поток комментариев поста, и в поток ответов родителя
+	r.publishCommentAdded(gqlComment)
+
+	return gqlComment, nil
 }
 
 // CreateUser is the resolver for the createUser field.
 func (r *mutationResolver) CreateUser(ctx context.Context, username string) (*graphModel.User, error) {
+	if err := r.Validator.Validate(validation.Value("username", validation.Username, username)); err != nil {
+		return nil, err
+	}
+
 	user := &commonModel.User{
 		ID:       uuid.New().String(),
 		Username: username,
 	}
 
-	if len(username) < 2 {
-		return nil, utils.NewGraphQLError("username должен быть длиной не меньше 2 символов", "400")
-	}
-
 	// Создаем пользователя
-	if err := r.Repo.CreateUser(user); err != nil {
-		fmt.Println("err:", err)
-		return nil, utils.NewGraphQLError("ошибка на сервере", "500")
+	if err := r.Repo.CreateUser(ctx, user); err != nil {
+		return nil, err
 	}
 
 	userGraphQL := graphModel.User{
@@ -146,26 +202,384 @@ func (r *mutationResolver) CreateUser(ctx context.Context, username string) (*gr
 	return &userGraphQL, nil
 }
 
+// UpdatePost меняет заголовок и/или текст поста
+func (r *mutationResolver) UpdatePost(ctx context.Context, id string, title *string, content *string) (*graphModel.Post, error) {
+	if title == nil && content == nil {
+		return nil, utils.NewGraphQLError("не указаны изменения", "400")
+	}
+
+	err := r.Validator.Validate(
+		validation.Optional("title", validation.PostTitle, title),
+		validation.Optional("content", validation.PostContent, content),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	post, err := r.Repo.GetPostByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	editor, err := r.requireAuthorOrModerator(ctx, post.AuthorID)
+	if err != nil {
+		return nil, err
+	}
+
+	post, err = r.Repo.UpdatePost(ctx, id, editor.ID, title, content)
+	if err != nil {
+		return nil, err
+	}
+
+	gqlPost := toGraphPost(post)
+	r.publishPostUpdated(gqlPost)
+
+	return gqlPost, nil
+}
+
+// DeletePost мягко удаляет пост
+func (r *mutationResolver) DeletePost(ctx context.Context, id string) (*graphModel.Post, error) {
+	post, err := r.Repo.GetPostByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	editor, err := r.requireAuthorOrModerator(ctx, post.AuthorID)
+	if err != nil {
+		return nil, err
+	}
+
+	post, err = r.Repo.DeletePost(ctx, id, editor.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Открытые клиенты заменяют пост заглушкой
+	gqlPost := toGraphPost(post)
+	r.publishPostUpdated(gqlPost)
+
+	return gqlPost, nil
+}
+
+// UpdateComment меняет текст комментария
+func (r *mutationResolver) UpdateComment(ctx context.Context, id string, content string) (*graphModel.Comment, error) {
+	if err := r.Validator.Validate(validation.Value("content", validation.CommentContent, content)); err != nil {
+		return nil, err
+	}
+
+	comment, err := r.Repo.GetCommentByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	editor, err := r.requireAuthorOrModerator(ctx, comment.AuthorID)
+	if err != nil {
+		return nil, err
+	}
+
+	comment, err = r.Repo.UpdateComment(ctx, id, editor.ID, content)
+	if err != nil {
+		return nil, err
+	}
+
+	gqlComment := toGraphComment(comment)
+	r.publishCommentUpdated(gqlComment)
+
+	return gqlComment, nil
+}
+
+// DeleteComment удаляет комментарий или оставляет заглушку, если у него есть ответы
+func (r *mutationResolver) DeleteComment(ctx context.Context, id string) (*graphModel.Comment, error) {
+	comment, err := r.Repo.GetCommentByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	editor, err := r.requireAuthorOrModerator(ctx, comment.AuthorID)
+	if err != nil {
+		return nil, err
+	}
+
+	comment, err = r.Repo.DeleteComment(ctx, id, editor.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	gqlComment := toGraphComment(comment)
+	r.publishCommentDeleted(gqlComment)
+
+	return gqlComment, nil
+}
+
+// Vote ставит, меняет или отменяет голос текущего пользователя
+func (r *mutationResolver) Vote(ctx context.Context, targetID string, value int32) (graphModel.Votable, error) {
+	user, err := r.requireViewer(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if value < -1 || value > 1 {
+		return nil, utils.NewGraphQLError("голос должен быть -1, 0 или 1", "400")
+	}
+
+	target, err := r.Repo.Vote(ctx, user.ID, targetID, int(value))
+	if err != nil {
+		return nil, err
+	}
+
+	return toGraphVotable(target), nil
+}
+
+// Bookmark сохраняет пост или комментарий в закладки текущего пользователя
+func (r *mutationResolver) Bookmark(ctx context.Context, targetID string) (graphModel.BookmarkNode, error) {
+	user, err := r.requireViewer(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	bookmark, err := r.Repo.AddBookmark(ctx, user.ID, targetID)
+	if err != nil {
+		return nil, err
+	}
+
+	return toGraphBookmarkNode(bookmark), nil
+}
+
+// Unbookmark убирает пост или комментарий из закладок текущего пользователя
+func (r *mutationResolver) Unbookmark(ctx context.Context, targetID string) (graphModel.BookmarkNode, error) {
+	user, err := r.requireViewer(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	bookmark, err := r.Repo.RemoveBookmark(ctx, user.ID, targetID)
+	if err != nil {
+		return nil, err
+	}
+
+	return toGraphBookmarkNode(bookmark), nil
+}
+
+// CreateForum добавляет раздел форума в конец списка
+func (r *mutationResolver) CreateForum(ctx context.Context, name string, description *string) (*graphModel.Forum, error) {
+	if _, err := r.requireAdmin(ctx); err != nil {
+		return nil, err
+	}
+
+	name = strings.TrimSpace(name)
+	err := r.Validator.Validate(
+		validation.Value("name", validation.ForumName, name),
+		validation.Optional("description", validation.ForumDescription, description),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	forum := &commonModel.Forum{
+		ID:   uuid.New().String(),
+		Name: name,
+	}
+	if description != nil {
+		forum.Description = *description
+	}
+
+	if err := r.Repo.CreateForum(ctx, forum); err != nil {
+		return nil, err
+	}
+
+	return toGraphForum(forum), nil
+}
+
+// ReorderForums меняет порядок разделов форума
+func (r *mutationResolver) ReorderForums(ctx context.Context, ids []string) ([]*graphModel.Forum, error) {
+	if _, err := r.requireAdmin(ctx); err != nil {
+		return nil, err
+	}
+
+	forums, err := r.Repo.ReorderForums(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+
+	return toGraphForums(forums), nil
+}
+
+// AddReaction ставит реакцию текущего пользователя на пост или комментарий
+func (r *mutationResolver) AddReaction(ctx context.Context, targetID string, emoji string) ([]*graphModel.Reaction, error) {
+	user, err := r.requireViewer(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := r.Validator.Validate(validation.Value("emoji", validation.Reaction, emoji)); err != nil {
+		return nil, err
+	}
+
+	change, err := r.Repo.AddReaction(ctx, user.ID, targetID, emoji)
+	if err != nil {
+		return nil, err
+	}
+
+	r.publishReactionsChanged(change, user, emoji, true)
+
+	return toGraphReactions(change.Reactions), nil
+}
+
+// RemoveReaction снимает реакцию текущего пользователя. Эмодзи не сверяется
+// со списком разрешённых, чтобы можно было снять реакцию, убранную из настроек
+func (r *mutationResolver) RemoveReaction(ctx context.Context, targetID string, emoji string) ([]*graphModel.Reaction, error) {
+	user, err := r.requireViewer(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	change, err := r.Repo.RemoveReaction(ctx, user.ID, targetID, emoji)
+	if err != nil {
+		return nil, err
+	}
+
+	r.publishReactionsChanged(change, user, emoji, false)
+
+	return toGraphReactions(change.Reactions), nil
+}
+
+// Typing включает или продлевает индикатор набора текста текущего пользователя
+func (r *mutationResolver) Typing(ctx context.Context, postID string, parentID *string) (bool, error) {
+	user, err := r.requireViewer(ctx)
+	if err != nil {
+		return false, err
+	}
+
+	// Проверяем, существует ли пост
+	if _, err := r.Repo.GetPostByID(ctx, postID); err != nil {
+		return false, err
+	}
+
+	parent := ""
+	if parentID != nil {
+		// Родительский комментарий должен быть в этом же посте
+		comment, err := r.Repo.GetCommentByID(ctx, *parentID)
+		if err != nil {
+			return false, err
+		}
+		if comment.PostID != postID {
+			return false, utils.NewGraphQLError("родительский коментарий не найден", "404")
+		}
+		parent = *parentID
+	}
+
+	err = r.PubSub.Typing.Touch(postID, parent, pubsub.Viewer{UserID: user.ID, Username: user.Username})
+	if errors.Is(err, pubsub.ErrRateLimited) {
+		return false, utils.NewGraphQLError("слишком частые запросы", "429")
+	}
+	if err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+// SetCommentsEnabled включает или отключает комментарии к посту
+func (r *mutationResolver) SetCommentsEnabled(ctx context.Context, postID string, enabled bool) (*graphModel.Post, error) {
+	post, err := r.Repo.GetPostByID(ctx, postID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Менять настройку может только автор поста или модератор
+	if _, err := r.requireAuthorOrModerator(ctx, post.AuthorID); err != nil {
+		return nil, err
+	}
+	if post.DeletedAt != nil {
+		return nil, repository.ErrPostDeleted
+	}
+
+	post, err = r.Repo.SetCommentsEnabled(ctx, postID, enabled)
+	if err != nil {
+		return nil, err
+	}
+
+	gqlPost := toGraphPost(post)
+
+	// Открытые клиенты прячут или показывают форму ответа
+	r.publishPostUpdated(gqlPost)
+
+	return gqlPost, nil
+}
+
+// ViewerVote загружает голос текущего пользователя пакетом для всех постов запроса
+func (r *postResolver) ViewerVote(ctx context.Context, obj *graphModel.Post) (*int32, error) {
+	return r.viewerVote(ctx, obj.ID)
+}
+
+// Reactions загружает реакции пакетом для всех постов запроса
+func (r *postResolver) Reactions(ctx context.Context, obj *graphModel.Post) ([]*graphModel.Reaction, error) {
+	return r.reactions(ctx, obj.ID)
+}
+
+// ViewerHasBookmarked загружает отметку закладки пакетом для всех постов запроса
+func (r *postResolver) ViewerHasBookmarked(ctx context.Context, obj *graphModel.Post) (bool, error) {
+	return r.viewerHasBookmarked(ctx, obj.ID)
+}
+
+// Forum загружает раздел поста пакетом для всех постов запроса
+func (r *postResolver) Forum(ctx context.Context, obj *graphModel.Post) (*graphModel.Forum, error) {
+	if obj.ForumID == nil {
+		return nil, nil
+	}
+
+	forum, err := r.loaders(ctx).Forums.Load(ctx, *obj.ForumID)
+	if err != nil || forum == nil {
+		return nil, err
+	}
+
+	return toGraphForum(forum), nil
+}
+
+// Comments загружает комментарии верхнего уровня пакетом для всех постов страницы
+func (r *postResolver) Comments(ctx context.Context, obj *graphModel.Post, limit *int32, offset *int32, orderBy *graphModel.SortOrder) ([]*graphModel.Comment, error) {
+	return loadComments(ctx, r.loaders(ctx).Comments, obj.ID, limit, offset, toSortOrder(orderBy, commonModel.OrderOldest))
+}
+
+// CommentsConnection возвращает страницу комментариев верхнего уровня поста
+func (r *postResolver) CommentsConnection(ctx context.Context, obj *graphModel.Post, first *int32, after *string, orderBy *graphModel.SortOrder) (*graphModel.CommentConnection, error) {
+	size, cursor, err := pageArgs(first, after)
+	if err != nil {
+		return nil, err
+	}
+
+	order := toSortOrder(orderBy, commonModel.OrderOldest)
+	page, err := r.Repo.GetCommentsPage(ctx, obj.ID, order, size, cursor)
+	if err != nil {
+		return nil, err
+	}
+
+	return toGraphCommentConnection(page, order, cursor), nil
+}
+
+// Revisions возвращает прежние версии поста автору и модераторам
+func (r *postResolver) Revisions(ctx context.Context, obj *graphModel.Post) ([]*graphModel.Revision, error) {
+	return r.revisions(ctx, obj.ID, obj.AuthorID)
+}
+
+// Diff сравнивает ревизии поста
+func (r *postResolver) Diff(ctx context.Context, obj *graphModel.Post, revisionA string, revisionB *string) (*graphModel.RevisionDiff, error) {
+	current := &commonModel.Revision{Title: &obj.Title, Content: obj.Content}
+	return r.revisionDiff(ctx, obj.ID, obj.AuthorID, revisionA, revisionB, current)
+}
+
 // Posts is the resolver for the posts field.
-func (r *queryResolver) Posts(ctx context.Context, offset int32, limit int32) ([]*graphModel.Post, error) {
+func (r *queryResolver) Posts(ctx context.Context, offset int32, limit int32, orderBy *graphModel.SortOrder, forum *string, tags []string) ([]*graphModel.Post, error) {
 	// Получаем посты из репозитория
-	posts, err := r.Repo.GetPosts(offset, limit)
+	posts, err := r.Repo.GetPosts(ctx, toSortOrder(orderBy, commonModel.OrderNewest), toPostFilter(forum, tags), offset, limit)
 	if err != nil {
-		return nil, utils.NewGraphQLError("ошибка на сервере", "500")
+		return nil, err
 	}
 
 	// Преобразуем в GraphQL-модель и добавим в список
 	var postsGraphQL []*graphModel.Post
 	for _, post := range posts {
-		postsGraphQL = append(postsGraphQL, &graphModel.Post{
-			ID:            post.ID,
-			Title:         post.Title,
-			Content:       post.Content,
-			AllowComments: post.AllowComments,
-			HaveComments:  post.HaveComments,
-			AuthorID:      post.AuthorID,
-			CreatedAt:     post.CreatedAt,
-		})
+		postsGraphQL = append(postsGraphQL, toGraphPost(post))
 	}
 
 	return postsGraphQL, nil
@@ -174,83 +588,390 @@ func (r *queryResolver) Posts(ctx context.Context, offset int32, limit int32) ([
 // Post is the resolver for the post field.
 func (r *queryResolver) Post(ctx context.Context, id string, offset int32, limit int32) (*graphModel.Post, error) {
 	// Получаем пост
-	post, err := r.Repo.GetPostByID(id)
+	post, err := r.Repo.GetPostByID(ctx, id)
 	if err != nil {
-		return nil, utils.NewGraphQLError("пост не найден", "404")
+		return nil, err
 	}
 
-	// Загружаем комментарии с пагинацией
-	comments, err := r.Repo.GetCommentsByPostID(post.ID, int(offset), int(limit))
+	// Комментарии загружает резолвер Post.comments с учётом offset и limit
+	return toGraphPost(post), nil
+}
+
+// GetReplies возвращает вложенные комментарии
+func (r *queryResolver) GetReplies(ctx context.Context, parentID string, offset int32, limit int32, orderBy *graphModel.SortOrder) ([]*graphModel.Comment, error) {
+	// Получаем список вложенных комментариев
+	replies, err := r.Repo.GetReplies(ctx, parentID, toSortOrder(orderBy, commonModel.OrderOldest), int(offset), int(limit))
 	if err != nil {
-		return nil, utils.NewGraphQLError("ошибка на сервере", "500")
+		return nil, err
+	}
+
+	// Преобразуем их в GraphQL-модель
+	return toGraphComments(replies), nil
+}
+
+// PostsConnection возвращает страницу постов в порядке orderBy с отбором по разделу и тегам
+func (r *queryResolver) PostsConnection(ctx context.Context, first *int32, after *string, orderBy *graphModel.SortOrder, forum *string, tags []string) (*graphModel.PostConnection, error) {
+	size, cursor, err := pageArgs(first, after)
+	if err != nil {
+		return nil, err
+	}
+
+	order := toSortOrder(orderBy, commonModel.OrderNewest)
+	page, err := r.Repo.GetPostsPage(ctx, order, toPostFilter(forum, tags), size, cursor)
+	if err != nil {
+		return nil, err
 	}
 
-	// Преобразуем комментарии в graphModel и добавим в список
-	var graphComments []*graphModel.Comment
-	for _, comment := range comments {
-		graphComments = append(graphComments, &graphModel.Comment{
-			ID:       comment.ID,
-			PostID:   comment.PostID,
-			ParentID: comment.ParentID,
-			Content:  comment.Content,
-			Author: &graphModel.User{
-				ID:       comment.Author.ID,
-				Username: comment.Author.Username,
-			},
-			HaveComments: comment.HaveComments,
-			CreatedAt:    comment.CreatedAt,
-		})
-	}
-
-	return &graphModel.Post{
-		ID:            post.ID,
-		Title:         post.Title,
-		Content:       post.Content,
-		AllowComments: post.AllowComments,
-		AuthorID:      post.AuthorID,
-		Comments:      graphComments,
-		CreatedAt:     post.CreatedAt,
+	return toGraphPostConnection(page, order, cursor), nil
+}
+
+// Viewer возвращает текущего пользователя; закладки загружает Viewer.bookmarks
+func (r *queryResolver) Viewer(ctx context.Context) (*graphModel.Viewer, error) {
+	user, err := r.viewer(ctx)
+	if err != nil || user == nil {
+		return nil, err
+	}
+
+	return &graphModel.Viewer{
+		User: &graphModel.User{ID: user.ID, Username: user.Username, CreatedAt: user.CreatedAt},
 	}, nil
 }
 
-// GetReplies возвращает вложенные комментарии
-func (r *queryResolver) GetReplies(ctx context.Context, parentID string, offset int32, limit int32) ([]*graphModel.Comment, error) {
-	// Получаем список вложенных комментариев
-	replies, err := r.Repo.GetReplies(parentID, int(offset), int(limit))
+// Forums возвращает разделы форума по порядку
+func (r *queryResolver) Forums(ctx context.Context) ([]*graphModel.Forum, error) {
+	forums, err := r.Repo.GetForums(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return toGraphForums(forums), nil
+}
+
+// Forum возвращает раздел форума по ID
+func (r *queryResolver) Forum(ctx context.Context, id string) (*graphModel.Forum, error) {
+	forum, err := r.Repo.GetForumByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	return toGraphForum(forum), nil
+}
+
+// Tags возвращает самые частые теги
+func (r *queryResolver) Tags(ctx context.Context, forum *string, first *int32) ([]*graphModel.Tag, error) {
+	size, err := pageSize(first)
+	if err != nil {
+		return nil, err
+	}
+
+	forumID := ""
+	if forum != nil {
+		forumID = *forum
+	}
+
+	tags, err := r.Repo.GetTags(ctx, forumID, size)
+	if err != nil {
+		return nil, err
+	}
+
+	return toGraphTags(tags), nil
+}
+
+// RepliesConnection возвращает страницу ответов на комментарий
+func (r *queryResolver) RepliesConnection(ctx context.Context, parentID string, first *int32, after *string, orderBy *graphModel.SortOrder) (*graphModel.CommentConnection, error) {
+	size, cursor, err := pageArgs(first, after)
+	if err != nil {
+		return nil, err
+	}
+
+	order := toSortOrder(orderBy, commonModel.OrderOldest)
+	page, err := r.Repo.GetRepliesPage(ctx, parentID, order, size, cursor)
+	if err != nil {
+		return nil, err
+	}
+
+	return toGraphCommentConnection(page, order, cursor), nil
+}
+
+// CommentTree возвращает ветку обсуждения поста до заданной глубины
+func (r *queryResolver) CommentTree(ctx context.Context, postID string, maxDepth int32, perLevelLimit int32) (*graphModel.CommentTree, error) {
+	if maxDepth < 0 || maxDepth > maxTreeDepth {
+		return nil, utils.NewGraphQLError(fmt.Sprintf("maxDepth должен быть от 0 до %d", maxTreeDepth), "400")
+	}
+	if perLevelLimit < 1 || perLevelLimit > maxPageSize {
+		return nil, utils.NewGraphQLError(fmt.Sprintf("perLevelLimit должен быть от 1 до %d", maxPageSize), "400")
+	}
+
+	// Проверяем, существует ли пост
+	if _, err := r.Repo.GetPostByID(ctx, postID); err != nil {
+		return nil, err
+	}
+
+	tree, err := r.Repo.GetCommentTree(ctx, postID, int(maxDepth), int(perLevelLimit))
+	if err != nil {
+		return nil, err
+	}
+
+	return toGraphCommentTree(tree), nil
+}
+
+// ThreadComments возвращает страницу комментариев поста в порядке обхода ветки в глубину
+func (r *queryResolver) ThreadComments(ctx context.Context, postID string, first *int32, after *string) (*graphModel.CommentConnection, error) {
+	size, path, err := threadPageArgs(first, after)
+	if err != nil {
+		return nil, err
+	}
+
+	// Проверяем, существует ли пост
+	if _, err := r.Repo.GetPostByID(ctx, postID); err != nil {
+		return nil, err
+	}
+
+	page, err := r.Repo.GetThreadPage(ctx, postID, size, path)
+	if err != nil {
+		return nil, err
+	}
+
+	return toGraphThreadConnection(page, after != nil), nil
+}
+
+// Search ищет посты и комментарии по тексту
+func (r *queryResolver) Search(ctx context.Context, query string, kind *graphModel.SearchKind, first *int32, after *string) (*graphModel.SearchConnection, error) {
+	if err := r.Validator.Validate(validation.Value("query", validation.SearchQuery, query)); err != nil {
+		return nil, err
+	}
+
+	size, cursor, err := pageArgs(first, after)
+	if err != nil {
+		return nil, err
+	}
+
+	searchKind := commonModel.SearchAll
+	if kind != nil {
+		searchKind = commonModel.SearchKind(*kind)
+	}
+
+	page, err := r.Repo.Search(ctx, query, searchKind, size, cursor)
+	if err != nil {
+		return nil, err
+	}
+
+	return toGraphSearchConnection(page, cursor), nil
+}
+
+// CommentAdded подписывает клиента на новые комментарии поста
+func (r *subscriptionResolver) CommentAdded(ctx context.Context, postID string, after *string, authorID *string) (<-chan *graphModel.Comment, error) {
+	// Проверяем, существует ли пост
+	if _, err := r.Repo.GetPostByID(ctx, postID); err != nil {
+		return nil, err
+	}
+
+	// Без курсора – только новые комментарии
+	cursor, err := replayCursor(after)
+	if err != nil {
+		return nil, err
+	}
+
+	ch, err := stream(ctx, r.PubSub.Comments, commentAddedTopic(postID), r.replayComments(postID, cursor), commentID, commentByAuthor(authorID))
+	if err != nil {
+		return nil, replayError(err)
+	}
+
+	return ch, nil
+}
+
+// ReplyAdded подписывает клиента на ответы к комментарию
+func (r *subscriptionResolver) ReplyAdded(ctx context.Context, parentID string, after *string, authorID *string) (<-chan *graphModel.Comment, error) {
+	// Проверяем, существует ли родительский комментарий
+	if _, err := r.Repo.GetCommentByID(ctx, parentID); err != nil {
+		return nil, err
+	}
+
+	// Без курсора – только новые ответы
+	cursor, err := replayCursor(after)
 	if err != nil {
+		return nil, err
+	}
+
+	ch, err := stream(ctx, r.PubSub.Comments, replyAddedTopic(parentID), r.replayReplies(parentID, cursor), commentID, commentByAuthor(authorID))
+	if err != nil {
+		return nil, replayError(err)
+	}
+
+	return ch, nil
+}
+
+// PostCreated подписывает клиента на новые посты
+func (r *subscriptionResolver) PostCreated(ctx context.Context, authorID *string) (<-chan *graphModel.Post, error) {
+	ch, err := stream(ctx, r.PubSub.Posts, postCreatedTopic, nil, graphPostID, postByAuthor(authorID))
+	if err != nil {
+		return nil, err
+	}
+
+	return ch, nil
+}
+
+// PostUpdated подписывает клиента на изменения поста или всех постов
+func (r *subscriptionResolver) PostUpdated(ctx context.Context, postID *string, authorID *string) (<-chan *graphModel.Post, error) {
+	topic := postsUpdatedTopic
+	if postID != nil {
+		// Проверяем, существует ли пост
+		if _, err := r.Repo.GetPostByID(ctx, *postID); err != nil {
+			return nil, err
+		}
+		topic = postUpdatedTopic(*postID)
+	}
+
+	ch, err := stream(ctx, r.PubSub.Posts, topic, nil, graphPostID, postByAuthor(authorID))
+	if err != nil {
+		return nil, err
+	}
+
+	return ch, nil
+}
+
+// CommentUpdated подписывает клиента на правки комментариев поста
+func (r *subscriptionResolver) CommentUpdated(ctx context.Context, postID string, after *string, authorID *string) (<-chan *graphModel.Comment, error) {
+	// Проверяем, существует ли пост
+	if _, err := r.Repo.GetPostByID(ctx, postID); err != nil {
+		return nil, err
+	}
+
+	// Без курсора – только новые правки. Комментарий, изменённый несколько раз,
+	// досылается один раз в текущем виде
+	cursor, err := replayCursor(after)
+	if err != nil {
+		return nil, err
+	}
+
+	ch, err := stream(ctx, r.PubSub.Comments, commentUpdatedTopic(postID), r.replayEdits(postID, cursor), commentEditID, commentByAuthor(authorID))
+	if err != nil {
+		return nil, replayError(err)
+	}
+
+	return ch, nil
+}
+
+// CommentDeleted подписывает клиента на удаление комментариев поста
+func (r *subscriptionResolver) CommentDeleted(ctx context.Context, postID string, authorID *string) (<-chan *graphModel.Comment, error) {
+	// Проверяем, существует ли пост
+	if _, err := r.Repo.GetPostByID(ctx, postID); err != nil {
+		return nil, err
+	}
+
+	ch, err := stream(ctx, r.PubSub.Comments, commentDeletedTopic(postID), nil, commentID, commentByAuthor(authorID))
+	if err != nil {
+		return nil, err
+	}
+
+	return ch, nil
+}
+
+// PostPresence подписывает клиента на число зрителей поста
+func (r *subscriptionResolver) PostPresence(ctx context.Context, postID string) (<-chan *graphModel.PostPresence, error) {
+	// Проверяем, существует ли пост
+	if _, err := r.Repo.GetPostByID(ctx, postID); err != nil {
+		return nil, err
+	}
+
+	// Представившийся пользователь виден по имени, остальные учитываются анонимно
+	user, err := r.viewer(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var viewer pubsub.Viewer
+	if user != nil {
+		viewer = pubsub.Viewer{UserID: user.ID, Username: user.Username}
+	}
+
+	leave := r.PubSub.Presence.Join(postID, viewer)
+
+	// Сразу отдаём текущее состояние, дальше – сглаженные изменения
+	current := func(ctx context.Context) ([]*graphModel.PostPresence, error) {
+		return []*graphModel.PostPresence{toGraphPresence(r.PubSub.Presence.Snapshot(postID))}, nil
+	}
+
+	ch, err := stream(ctx, r.PubSub.PresenceEvents, presenceTopic(postID), current, nil, nil)
+	if err != nil {
+		leave()
 		return nil, utils.NewGraphQLError("ошибка на сервере", "500")
 	}
 
-	// Преобразуем их в GraphQL-модель
-	var gqlReplies []*graphModel.Comment
-	for _, c := range replies {
-		gqlReplies = append(gqlReplies, &graphModel.Comment{
-			ID:        c.ID,
-			PostID:    c.PostID,
-			ParentID:  c.ParentID,
-			Content:   c.Content,
-			CreatedAt: c.CreatedAt,
-			Author:    (*graphModel.User)(c.Author),
-		})
+	// Зритель уходит вместе с подпиской
+	go func() {
+		<-ctx.Done()
+		leave()
+	}()
+
+	return ch, nil
+}
+
+// TypingActivity подписывает клиента на индикаторы набора текста в посте
+func (r *subscriptionResolver) TypingActivity(ctx context.Context, postID string) (<-chan *graphModel.TypingEvent, error) {
+	// Проверяем, существует ли пост
+	if _, err := r.Repo.GetPostByID(ctx, postID); err != nil {
+		return nil, err
+	}
+
+	ch, err := stream(ctx, r.PubSub.TypingEvents, typingTopic(postID), nil, nil, nil)
+	if err != nil {
+		return nil, err
 	}
 
-	return gqlReplies, nil
+	return ch, nil
 }
 
-// CommentAdded is the resolver for the commentAdded field.
-func (r *subscriptionResolver) CommentAdded(ctx context.Context, postID string) (<-chan *graphModel.Comment, error) {
-	return nil, nil
+// ReactionsChanged подписывает клиента на реакции к посту и его комментариям
+func (r *subscriptionResolver) ReactionsChanged(ctx context.Context, postID string) (<-chan *graphModel.ReactionsChangedEvent, error) {
+	// Проверяем, существует ли пост
+	if _, err := r.Repo.GetPostByID(ctx, postID); err != nil {
+		return nil, err
+	}
+
+	ch, err := stream(ctx, r.PubSub.Reactions, reactionsChangedTopic(postID), nil, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return ch, nil
+}
+
+// Bookmarks возвращает страницу закладок текущего пользователя
+func (r *viewerResolver) Bookmarks(ctx context.Context, obj *graphModel.Viewer, first *int32, after *string) (*graphModel.BookmarkConnection, error) {
+	size, cursor, err := pageArgs(first, after)
+	if err != nil {
+		return nil, err
+	}
+
+	page, err := r.Repo.GetBookmarks(ctx, obj.User.ID, size, cursor)
+	if err != nil {
+		return nil, err
+	}
+
+	return toGraphBookmarkConnection(page, cursor), nil
 }
 
+// Comment returns CommentResolver implementation.
+func (r *Resolver) Comment() CommentResolver { return &commentResolver{r} }
+
 // Mutation returns MutationResolver implementation.
 func (r *Resolver) Mutation() MutationResolver { return &mutationResolver{r} }
 
+// Post returns PostResolver implementation.
+func (r *Resolver) Post() PostResolver { return &postResolver{r} }
+
 // Query returns QueryResolver implementation.
 func (r *Resolver) Query() QueryResolver { return &queryResolver{r} }
 
 // Subscription returns SubscriptionResolver implementation.
 func (r *Resolver) Subscription() SubscriptionResolver { return &subscriptionResolver{r} }
 
+// Viewer returns ViewerResolver implementation.
+func (r *Resolver) Viewer() ViewerResolver { return &viewerResolver{r} }
+
+type commentResolver struct{ *Resolver }
 type mutationResolver struct{ *Resolver }
+type postResolver struct{ *Resolver }
 type queryResolver struct{ *Resolver }
 type subscriptionResolver struct{ *Resolver }
+type viewerResolver struct{ *Resolver }
@@ -0,0 +1,222 @@
+package graph
+
+import (
+	"context"
+	"errors"
+
+	graphModel "github.com/22Fariz22/forum/graph/model"
+	commonModel "github.com/22Fariz22/forum/internal/model"
+	"github.com/22Fariz22/forum/pubsub"
+	"github.com/22Fariz22/forum/utils"
+)
+
+// maxReplayEvents – сколько пропущенных событий можно дослать при переподключении.
+// Если пропущено больше, клиенту проще перезагрузить ветку целиком.
+const maxReplayEvents = 1000
+
+// errReplayTooLarge – клиент пропустил больше maxReplayEvents событий
+var errReplayTooLarge = errors.New("слишком много пропущенных событий")
+
+// replayFunc загружает из хранилища события, пропущенные клиентом
+type replayFunc[T any] func(ctx context.Context) ([]T, error)
+
+// filterFunc отбирает события, которые нужны клиенту
+type filterFunc[T any] func(msg T) bool
+
+// stream подписывается на тему и отдаёт клиенту сначала пропущенные события
+// из хранилища, затем живые. Подписка оформляется до загрузки пропущенных,
+// поэтому разрывов нет, а повторно пришедшие вживую события отбрасываются по ID
+// (если id не задан, дедупликации нет). События, не прошедшие filter,
+// клиенту не отправляются.
+func stream[T any](ctx context.Context, broker pubsub.Broker[T], topic string, replay replayFunc[T], id func(T) string, filter filterFunc[T]) (<-chan T, error) {
+	sub, err := broker.Subscribe(topic)
+	if err != nil {
+		return nil, err
+	}
+
+	var missed []T
+	if replay != nil {
+		missed, err = replay(ctx)
+		if err != nil {
+			broker.Unsubscribe(sub)
+			return nil, err
+		}
+	}
+
+	out := make(chan T, 1)
+
+	go func() {
+		// Отписываемся, как только клиент отключился
+		defer close(out)
+		defer broker.Unsubscribe(sub)
+
+		send := func(msg T) bool {
+			if filter != nil && !filter(msg) {
+				return true
+			}
+
+			select {
+			case out <- msg:
+				return true
+			case <-ctx.Done():
+				return false
+			}
+		}
+
+		replayed := make(map[string]struct{}, len(missed))
+		for _, msg := range missed {
+			if id != nil {
+				replayed[id(msg)] = struct{}{}
+			}
+			if !send(msg) {
+				return
+			}
+		}
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case msg, ok := <-sub.C():
+				// Канал закрыт: PubSub остановлен или клиент не успевал читать
+				if !ok {
+					return
+				}
+
+				if id != nil {
+					if _, dup := replayed[id(msg)]; dup {
+						delete(replayed, id(msg))
+						continue
+					}
+				}
+
+				if !send(msg) {
+					return
+				}
+			}
+		}
+	}()
+
+	return out, nil
+}
+
+// commentID – ключ дедупликации комментариев
+func commentID(c *graphModel.Comment) string {
+	return c.ID
+}
+
+// commentEditID – ключ дедупликации правок: одна и та же правка, пришедшая
+// и из хранилища, и вживую, отбрасывается, а более поздняя – нет
+func commentEditID(c *graphModel.Comment) string {
+	if c.EditCursor == nil {
+		return c.ID
+	}
+	return *c.EditCursor
+}
+
+// replayPage загружает из хранилища до limit комментариев после курсора
+type replayPage func(ctx context.Context, after commonModel.Cursor, limit int) ([]*commonModel.Comment, error)
+
+// replayPages дозагружает комментарии страницами от курсора after, пока они
+// не кончатся. next возвращает курсор последнего комментария страницы.
+// Без курсора загружать нечего: клиент получит только новые события
+func replayPages(after *commonModel.Cursor, load replayPage, next func(*commonModel.Comment) commonModel.Cursor) replayFunc[*graphModel.Comment] {
+	if after == nil {
+		return nil
+	}
+	cursor := *after
+
+	return func(ctx context.Context) ([]*graphModel.Comment, error) {
+		const pageSize = 100
+
+		var missed []*graphModel.Comment
+		for {
+			page, err := load(ctx, cursor, pageSize)
+			if err != nil {
+				return nil, err
+			}
+
+			missed = append(missed, toGraphComments(page)...)
+			if len(missed) > maxReplayEvents {
+				return nil, errReplayTooLarge
+			}
+			if len(page) < pageSize {
+				return missed, nil
+			}
+
+			cursor = next(page[len(page)-1])
+		}
+	}
+}
+
+// replayComments загружает комментарии поста, созданные после курсора
+func (r *Resolver) replayComments(postID string, after *commonModel.Cursor) replayFunc[*graphModel.Comment] {
+	return replayPages(after, func(ctx context.Context, after commonModel.Cursor, limit int) ([]*commonModel.Comment, error) {
+		return r.Repo.GetCommentsAfter(ctx, postID, after, limit)
+	}, commonModel.CursorOf)
+}
+
+// replayReplies загружает ответы на комментарий, созданные после курсора
+func (r *Resolver) replayReplies(parentID string, after *commonModel.Cursor) replayFunc[*graphModel.Comment] {
+	return replayPages(after, func(ctx context.Context, after commonModel.Cursor, limit int) ([]*commonModel.Comment, error) {
+		page, err := r.Repo.GetRepliesPage(ctx, parentID, commonModel.OrderOldest, limit, &after)
+		if err != nil {
+			return nil, err
+		}
+		return page.Items, nil
+	}, commonModel.CursorOf)
+}
+
+// replayEdits загружает комментарии поста, изменённые после курсора
+func (r *Resolver) replayEdits(postID string, after *commonModel.Cursor) replayFunc[*graphModel.Comment] {
+	return replayPages(after, func(ctx context.Context, after commonModel.Cursor, limit int) ([]*commonModel.Comment, error) {
+		return r.Repo.GetCommentsEditedAfter(ctx, postID, after, limit)
+	}, commonModel.EditCursorOf)
+}
+
+// replayCursor разбирает курсор after подписки; nil – курсора нет
+func replayCursor(after *string) (*commonModel.Cursor, error) {
+	if after == nil {
+		return nil, nil
+	}
+
+	cursor, err := commonModel.DecodeCursor(*after)
+	if err != nil {
+		return nil, utils.NewGraphQLError("некорректный курсор", "400")
+	}
+
+	return &cursor, nil
+}
+
+// replayError заменяет errReplayTooLarge понятной клиенту ошибкой
+func replayError(err error) error {
+	if errors.Is(err, errReplayTooLarge) {
+		return utils.NewGraphQLError("курсор устарел, загрузите комментарии заново", "410")
+	}
+	return err
+}
+
+// graphPostID – ключ дедупликации постов
+func graphPostID(p *graphModel.Post) string {
+	return p.ID
+}
+
+// commentByAuthor отбирает комментарии автора; nil – без фильтра
+func commentByAuthor(authorID *string) filterFunc[*graphModel.Comment] {
+	if authorID == nil {
+		return nil
+	}
+	return func(c *graphModel.Comment) bool {
+		return c.Author != nil && c.Author.ID == *authorID
+	}
+}
+
+// postByAuthor отбирает посты автора; nil – без фильтра
+func postByAuthor(authorID *string) filterFunc[*graphModel.Post] {
+	if authorID == nil {
+		return nil
+	}
+	return func(p *graphModel.Post) bool {
+		return p.AuthorID == *authorID
+	}
+}
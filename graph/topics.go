@@ -0,0 +1,87 @@
+package graph
+
+import (
+	graphModel "github.com/22Fariz22/forum/graph/model"
+	commonModel "github.com/22Fariz22/forum/internal/model"
+)
+
+// Темы pubsub, в которые мутации публикуют события подписок
+
+// commentAddedTopic – новые комментарии поста (всех уровней)
+func commentAddedTopic(postID string) string {
+	return "post:" + postID + ":comments"
+}
+
+// replyAddedTopic – ответы на комментарий
+func replyAddedTopic(parentID string) string {
+	return "comment:" + parentID + ":replies"
+}
+
+// commentUpdatedTopic – изменённые комментарии поста
+func commentUpdatedTopic(postID string) string {
+	return "post:" + postID + ":comment-updated"
+}
+
+// commentDeletedTopic – удалённые комментарии поста
+func commentDeletedTopic(postID string) string {
+	return "post:" + postID + ":comment-deleted"
+}
+
+// postCreatedTopic – новые посты
+const postCreatedTopic = "posts:created"
+
+// postsUpdatedTopic – изменения любых постов
+const postsUpdatedTopic = "posts:updated"
+
+// postUpdatedTopic – изменения конкретного поста
+func postUpdatedTopic(postID string) string {
+	return "post:" + postID + ":updated"
+}
+
+// presenceTopic – зрители поста
+func presenceTopic(postID string) string {
+	return "post:" + postID + ":presence"
+}
+
+// typingTopic – индикаторы набора текста в посте
+func typingTopic(postID string) string {
+	return "post:" + postID + ":typing"
+}
+
+// reactionsChangedTopic – реакции на пост и его комментарии
+func reactionsChangedTopic(postID string) string {
+	return "post:" + postID + ":reactions"
+}
+
+// publishPostUpdated публикует изменение поста в общую и персональную темы
+func (r *Resolver) publishPostUpdated(post *graphModel.Post) {
+	r.PubSub.Posts.Publish(postsUpdatedTopic, post)
+	r.PubSub.Posts.Publish(postUpdatedTopic(post.ID), post)
+}
+
+// publishCommentAdded публикует новый комментарий в ленту поста и,
+// если это ответ, в ленту ответов родителя
+func (r *Resolver) publishCommentAdded(comment *graphModel.Comment) {
+	r.PubSub.Comments.Publish(commentAddedTopic(comment.PostID), comment)
+	if comment.ParentID != nil {
+		r.PubSub.Comments.Publish(replyAddedTopic(*comment.ParentID), comment)
+	}
+}
+
+// publishReactionsChanged публикует изменение реакций, если оно было
+func (r *Resolver) publishReactionsChanged(change *commonModel.ReactionChange, user *commonModel.User, emoji string, added bool) {
+	if !change.Changed {
+		return
+	}
+	r.PubSub.Reactions.Publish(reactionsChangedTopic(change.PostID), toGraphReactionsChangedEvent(change, user, emoji, added))
+}
+
+// publishCommentUpdated публикует изменённый комментарий в ленту правок поста
+func (r *Resolver) publishCommentUpdated(comment *graphModel.Comment) {
+	r.PubSub.Comments.Publish(commentUpdatedTopic(comment.PostID), comment)
+}
+
+// publishCommentDeleted публикует удалённый комментарий в ленту удалений поста
+func (r *Resolver) publishCommentDeleted(comment *graphModel.Comment) {
+	r.PubSub.Comments.Publish(commentDeletedTopic(comment.PostID), comment)
+}
@@ -0,0 +1,95 @@
+package graph
+
+import (
+	"context"
+	"errors"
+
+	"github.com/22Fariz22/forum/internal/auth"
+	commonModel "github.com/22Fariz22/forum/internal/model"
+	"github.com/22Fariz22/forum/internal/repository"
+	"github.com/22Fariz22/forum/utils"
+)
+
+// viewer возвращает текущего пользователя или nil, если он не представился
+// или не существует в хранилище. Сбой хранилища возвращается как ошибка.
+func (r *Resolver) viewer(ctx context.Context) (*commonModel.User, error) {
+	userID, ok := auth.UserID(ctx)
+	if !ok {
+		return nil, nil
+	}
+
+	user, err := r.Repo.GetUserByID(ctx, userID)
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return user, nil
+}
+
+// requireViewer возвращает текущего пользователя или ошибку 401.
+// Сбой хранилища не выдаётся за отсутствие авторизации.
+func (r *Resolver) requireViewer(ctx context.Context) (*commonModel.User, error) {
+	userID, ok := auth.UserID(ctx)
+	if !ok {
+		return nil, utils.NewGraphQLError("требуется авторизация", "401")
+	}
+
+	user, err := r.Repo.GetUserByID(ctx, userID)
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil, utils.NewGraphQLError("требуется авторизация", "401")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return user, nil
+}
+
+// requireAuthor возвращает текущего пользователя как автора новой записи.
+// Устаревший аргумент author допускается, только если совпадает с ним:
+// иначе можно было бы писать от чужого имени
+func (r *Resolver) requireAuthor(ctx context.Context, author *string) (*commonModel.User, error) {
+	user, err := r.requireViewer(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if author != nil && *author != user.ID {
+		return nil, utils.NewGraphQLError("автор не совпадает с текущим пользователем", "403")
+	}
+
+	return user, nil
+}
+
+// requireAuthorOrModerator возвращает текущего пользователя, если он автор
+// сущности или модератор, иначе ошибку 401/403
+func (r *Resolver) requireAuthorOrModerator(ctx context.Context, authorID string) (*commonModel.User, error) {
+	user, err := r.requireViewer(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if user.ID != authorID && !r.Roles.IsModerator(user.ID) {
+		return nil, utils.NewGraphQLError("недостаточно прав", "403")
+	}
+
+	return user, nil
+}
+
+// requireAdmin возвращает текущего пользователя, если он администратор,
+// иначе ошибку 401/403
+func (r *Resolver) requireAdmin(ctx context.Context) (*commonModel.User, error) {
+	user, err := r.requireViewer(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if !r.Roles.IsAdmin(user.ID) {
+		return nil, utils.NewGraphQLError("недостаточно прав", "403")
+	}
+
+	return user, nil
+}
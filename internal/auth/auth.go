@@ -0,0 +1,41 @@
+package auth
+
+import (
+	"context"
+	"net/http"
+)
+
+// Header – заголовок с ID текущего пользователя. Это единственный источник
+// личности для всех мутаций. Сервер не проверяет подлинность заголовка,
+// поэтому его должен выставлять доверенный прокси после аутентификации,
+// отбрасывая одноимённый заголовок клиента
+const Header = "X-User-ID"
+
+// InitPayloadKey – ключ с ID пользователя в connection_init у WebSocket
+const InitPayloadKey = "userID"
+
+type ctxKey struct{}
+
+// WithUserID кладёт ID текущего пользователя в контекст
+func WithUserID(ctx context.Context, userID string) context.Context {
+	if userID == "" {
+		return ctx
+	}
+	return context.WithValue(ctx, ctxKey{}, userID)
+}
+
+// UserID возвращает ID текущего пользователя, если он представился
+func UserID(ctx context.Context) (string, bool) {
+	userID, ok := ctx.Value(ctxKey{}).(string)
+	return userID, ok && userID != ""
+}
+
+// Middleware достаёт ID пользователя из заголовка запроса
+func Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if userID := r.Header.Get(Header); userID != "" {
+			r = r.WithContext(WithUserID(r.Context(), userID))
+		}
+		next.ServeHTTP(w, r)
+	})
+}
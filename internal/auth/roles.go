@@ -0,0 +1,39 @@
+package auth
+
+// Roles хранит роли пользователей, заданные в конфигурации
+type Roles struct {
+	moderators map[string]struct{}
+	admins     map[string]struct{}
+}
+
+// NewRoles создаёт роли по спискам ID модераторов и администраторов
+func NewRoles(moderatorIDs, adminIDs []string) *Roles {
+	return &Roles{moderators: idSet(moderatorIDs), admins: idSet(adminIDs)}
+}
+
+func idSet(ids []string) map[string]struct{} {
+	set := make(map[string]struct{}, len(ids))
+	for _, id := range ids {
+		set[id] = struct{}{}
+	}
+	return set
+}
+
+// IsModerator сообщает, является ли пользователь модератором.
+// Администраторы тоже модерируют
+func (r *Roles) IsModerator(userID string) bool {
+	if r == nil {
+		return false
+	}
+	_, ok := r.moderators[userID]
+	return ok || r.IsAdmin(userID)
+}
+
+// IsAdmin сообщает, является ли пользователь администратором
+func (r *Roles) IsAdmin(userID string) bool {
+	if r == nil {
+		return false
+	}
+	_, ok := r.admins[userID]
+	return ok
+}
@@ -0,0 +1,20 @@
+package model
+
+import "time"
+
+// Bookmark – пост или комментарий, сохранённый пользователем. Закладки
+// удаляются вместе с записью
+type Bookmark struct {
+	UserID    string    `json:"userID" db:"user_id" gorm:"primaryKey;type:uuid"`
+	TargetID  string    `json:"targetID" db:"target_id" gorm:"primaryKey;type:uuid"` // ID поста или комментария
+	CreatedAt time.Time `json:"createdAt" db:"created_at" gorm:"type:timestamp;default:CURRENT_TIMESTAMP"`
+
+	// Сохранённая запись: заполнено одно из полей
+	Post    *Post    `json:"-" db:"-" gorm:"-"`
+	Comment *Comment `json:"-" db:"-" gorm:"-"`
+}
+
+// Cursor возвращает курсор закладки. Закладки идут от новых к старым
+func (b *Bookmark) Cursor() Cursor {
+	return Cursor{CreatedAt: b.CreatedAt, ID: b.TargetID}
+}
@@ -0,0 +1,95 @@
+package model
+
+import (
+	"encoding/base64"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrInvalidCursor – курсор не удалось разобрать
+var ErrInvalidCursor = errors.New("некорректный курсор")
+
+// Cursor – позиция в ленте, упорядоченной по (created_at, id) или,
+// для порядков SortOrder.Ranked, по (вес, created_at, id)
+type Cursor struct {
+	Rank      float64
+	CreatedAt time.Time
+	ID        string
+}
+
+// Encode кодирует курсор в непрозрачную строку для клиента.
+// Нулевой вес не кодируется, поэтому курсоры по времени не меняются.
+func (c Cursor) Encode() string {
+	raw := c.CreatedAt.UTC().Format(time.RFC3339Nano) + "|" + c.ID
+	if c.Rank != 0 {
+		raw = strconv.FormatFloat(c.Rank, 'g', -1, 64) + "|" + raw
+	}
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+// Less сообщает, находится ли позиция (createdAt, id) строго после курсора
+func (c Cursor) Less(createdAt time.Time, id string) bool {
+	if !c.CreatedAt.Equal(createdAt) {
+		return c.CreatedAt.Before(createdAt)
+	}
+	return c.ID < id
+}
+
+// Greater сообщает, находится ли позиция (createdAt, id) строго перед курсором
+func (c Cursor) Greater(createdAt time.Time, id string) bool {
+	if !c.CreatedAt.Equal(createdAt) {
+		return c.CreatedAt.After(createdAt)
+	}
+	return c.ID > id
+}
+
+// DecodeCursor разбирает строку, полученную из Cursor.Encode
+func DecodeCursor(s string) (Cursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return Cursor{}, ErrInvalidCursor
+	}
+
+	var cursor Cursor
+
+	parts := strings.Split(string(raw), "|")
+	switch len(parts) {
+	case 2:
+	case 3:
+		if cursor.Rank, err = strconv.ParseFloat(parts[0], 64); err != nil {
+			return Cursor{}, ErrInvalidCursor
+		}
+		parts = parts[1:]
+	default:
+		return Cursor{}, ErrInvalidCursor
+	}
+
+	if parts[1] == "" {
+		return Cursor{}, ErrInvalidCursor
+	}
+	cursor.ID = parts[1]
+
+	if cursor.CreatedAt, err = time.Parse(time.RFC3339Nano, parts[0]); err != nil {
+		return Cursor{}, ErrInvalidCursor
+	}
+
+	return cursor, nil
+}
+
+// CursorOf возвращает курсор, указывающий на комментарий
+func CursorOf(c *Comment) Cursor {
+	return Cursor{CreatedAt: c.CreatedAt, ID: c.ID}
+}
+
+// EditCursorOf возвращает курсор, указывающий на последнюю правку комментария,
+// в порядке (edited_at, id). Комментарий должен быть изменён: EditedAt != nil
+func EditCursorOf(c *Comment) Cursor {
+	return Cursor{CreatedAt: *c.EditedAt, ID: c.ID}
+}
+
+// PostCursorOf возвращает курсор, указывающий на пост
+func PostCursorOf(p *Post) Cursor {
+	return Cursor{CreatedAt: p.CreatedAt, ID: p.ID}
+}
@@ -0,0 +1,70 @@
+package model
+
+import (
+	"strings"
+	"time"
+)
+
+// Forum – раздел форума. Position задаёт порядок разделов в списке,
+// его меняет администратор
+type Forum struct {
+	ID          string    `json:"id" db:"id" gorm:"primaryKey;type:uuid"`
+	Name        string    `json:"name" db:"name" gorm:"type:varchar(50);not null;uniqueIndex"`
+	Description string    `json:"description" db:"description" gorm:"type:text;not null;default:''"`
+	Position    int       `json:"position" db:"position" gorm:"not null;default:0"`
+	CreatedAt   time.Time `json:"createdAt" db:"created_at" gorm:"type:timestamp;default:CURRENT_TIMESTAMP"`
+}
+
+// Tag – тег и число неудалённых постов с ним
+type Tag struct {
+	Name      string
+	PostCount int
+}
+
+// PostFilter – отбор постов ленты. Пустой ForumID – посты всех разделов;
+// пост должен содержать все теги Tags
+type PostFilter struct {
+	ForumID string
+	Tags    []string
+}
+
+// NormalizeTags приводит теги к нижнему регистру, обрезает пробелы и
+// убирает повторы, сохраняя порядок
+func NormalizeTags(tags []string) []string {
+	seen := make(map[string]bool, len(tags))
+	result := make([]string, 0, len(tags))
+	for _, tag := range tags {
+		tag = strings.ToLower(strings.TrimSpace(tag))
+		if seen[tag] {
+			continue
+		}
+		seen[tag] = true
+		result = append(result, tag)
+	}
+	return result
+}
+
+// HasTags сообщает, что у поста есть все теги tags
+func (p *Post) HasTags(tags []string) bool {
+	for _, tag := range tags {
+		found := false
+		for _, t := range p.Tags {
+			if t == tag {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// Match сообщает, проходит ли пост через фильтр
+func (f PostFilter) Match(p *Post) bool {
+	if f.ForumID != "" && (p.ForumID == nil || *p.ForumID != f.ForumID) {
+		return false
+	}
+	return p.HasTags(f.Tags)
+}
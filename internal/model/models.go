@@ -10,6 +10,9 @@ type User struct {
 	CreatedAt time.Time `json:"createdAt" gorm:"type:timestamp;default:CURRENT_TIMESTAMP"`
 }
 
+// DeletedContent заменяет заголовок и текст удалённого поста или комментария
+const DeletedContent = "[deleted]"
+
 // Post – модель поста
 type Post struct {
 	ID            string     `json:"id" gorm:"primaryKey;type:uuid"`
@@ -17,9 +20,24 @@ type Post struct {
 	Content       string     `json:"content" gorm:"type:text;not null"`
 	AllowComments bool       `json:"allowComments" gorm:"default:true"`
 	AuthorID      string     `json:"authorID" gorm:"type:uuid;not null"`
-	HaveComments  bool       `json:"haveComments" gorm:"default:false"`
+	HaveComments  bool       `json:"haveComments" gorm:"default:false"` // CommentCount > 0
 	Comments      []*Comment `json:"comments" gorm:"-"`
 	CreatedAt     time.Time  `json:"createdAt" gorm:"type:timestamp;default:CURRENT_TIMESTAMP"`
+	// Число комментариев всех уровней, обновляется в одной транзакции со вставкой
+	CommentCount int `json:"commentCount" db:"comment_count" gorm:"not null;default:0"`
+	// Голоса и рейтинг Score = Upvotes - Downvotes, меняются атомарно вместе с голосом.
+	// Рейтинг используется порядками TOP и HOT
+	Upvotes   int        `json:"upvotes" db:"upvotes" gorm:"not null;default:0"`
+	Downvotes int        `json:"downvotes" db:"downvotes" gorm:"not null;default:0"`
+	Score     int        `json:"score" db:"score" gorm:"not null;default:0"`
+	EditedAt  *time.Time `json:"editedAt" db:"edited_at" gorm:"type:timestamp"`
+	// Удалённый пост остаётся доступным по ID, но пропадает из ленты
+	DeletedAt *time.Time `json:"deletedAt" db:"deleted_at" gorm:"type:timestamp"`
+	// Раздел форума, nil – пост вне разделов
+	ForumID *string `json:"forumID" db:"forum_id" gorm:"type:uuid"`
+	// Теги в нижнем регистре без повторов (NormalizeTags). Столбец text[]
+	// создаётся миграцией: GORM не отображает срезы строк
+	Tags []string `json:"tags" db:"tags" gorm:"-"`
 }
 
 // Comment – модель комментария
@@ -31,6 +49,20 @@ type Comment struct {
 	Author       *User     `json:"author" gorm:"-"`
 	AuthorID     string    `json:"authorID" db:"author_id" gorm:"foreignKey:AuthorID;references:ID"`
 	Username     string    `json:"username" db:"username" gorm:"type:varchar(20);not null"`
-	HaveComments bool      `json:"haveComments" db:"have_comments" gorm:"default:false"`
+	HaveComments bool      `json:"haveComments" db:"have_comments" gorm:"default:false"` // ReplyCount > 0
 	CreatedAt    time.Time `json:"createdAt" gorm:"type:timestamp;default:CURRENT_TIMESTAMP"`
+	// Материализованный путь: сегменты PathSegment всех предков и самого комментария.
+	// Сортировка по пути даёт обход ветки в глубину
+	Path  string `json:"path" db:"path" gorm:"type:text"`
+	Depth int    `json:"depth" db:"depth" gorm:"not null;default:0"` // 0 – комментарий верхнего уровня
+	// Число прямых ответов, обновляется в одной транзакции со вставкой ответа
+	ReplyCount int `json:"replyCount" db:"reply_count" gorm:"not null;default:0"`
+	// Голоса и рейтинг Score = Upvotes - Downvotes, меняются атомарно вместе с голосом.
+	// Рейтинг используется порядками TOP и HOT
+	Upvotes   int        `json:"upvotes" db:"upvotes" gorm:"not null;default:0"`
+	Downvotes int        `json:"downvotes" db:"downvotes" gorm:"not null;default:0"`
+	Score     int        `json:"score" db:"score" gorm:"not null;default:0"`
+	EditedAt  *time.Time `json:"editedAt" db:"edited_at" gorm:"type:timestamp"`
+	// Удалённый комментарий с ответами остаётся в ветке заглушкой DeletedContent
+	DeletedAt *time.Time `json:"deletedAt" db:"deleted_at" gorm:"type:timestamp"`
 }
@@ -0,0 +1,90 @@
+package model
+
+import (
+	"math"
+	"time"
+)
+
+// SortOrder – порядок постов, комментариев и ответов в списках
+type SortOrder string
+
+const (
+	// OrderNewest – от новых к старым
+	OrderNewest SortOrder = "NEWEST"
+	// OrderOldest – от старых к новым
+	OrderOldest SortOrder = "OLDEST"
+	// OrderTop – по рейтингу
+	OrderTop SortOrder = "TOP"
+	// OrderHot – по рейтингу и числу ответов с поправкой на возраст
+	OrderHot SortOrder = "HOT"
+	// OrderMostReplied – по числу комментариев поста или прямых ответов комментария
+	OrderMostReplied SortOrder = "MOST_REPLIED"
+)
+
+// HotPeriod – за столько секунд вес HOT растёт на единицу: запись, набравшая
+// в 10 раз больше активности, равна записи, созданной на HotPeriod позже
+const HotPeriod = 45000
+
+// Valid сообщает, известен ли порядок
+func (o SortOrder) Valid() bool {
+	switch o {
+	case OrderNewest, OrderOldest, OrderTop, OrderHot, OrderMostReplied:
+		return true
+	}
+	return false
+}
+
+// Ranked сообщает, сортирует ли порядок по вычисляемому весу (Cursor.Rank).
+// Записи с равным весом идут от новых к старым.
+func (o SortOrder) Ranked() bool {
+	return o == OrderTop || o == OrderHot || o == OrderMostReplied
+}
+
+// HotRank – вес записи для OrderHot. Не зависит от текущего времени, поэтому
+// курсоры остаются стабильными. Postgres считает вес тем же выражением в SQL
+// и сравнивает страницы с весом строки курсора, а не с этим значением.
+func HotRank(activity int, createdAt time.Time) float64 {
+	a := float64(activity)
+	sign := 0.0
+	switch {
+	case a > 0:
+		sign = 1
+	case a < 0:
+		sign = -1
+	}
+	return sign*math.Log10(math.Max(math.Abs(a), 1)) + float64(createdAt.UnixMicro())/1e6/HotPeriod
+}
+
+// rank – вес записи для порядка o, 0 для порядков по времени
+func (o SortOrder) rank(score, replies int, createdAt time.Time) float64 {
+	switch o {
+	case OrderTop:
+		return float64(score)
+	case OrderMostReplied:
+		return float64(replies)
+	case OrderHot:
+		return HotRank(score+replies, createdAt)
+	}
+	return 0
+}
+
+// PostCursor возвращает курсор поста для порядка o
+func (o SortOrder) PostCursor(p *Post) Cursor {
+	return Cursor{Rank: o.rank(p.Score, p.CommentCount, p.CreatedAt), CreatedAt: p.CreatedAt, ID: p.ID}
+}
+
+// CommentCursor возвращает курсор комментария для порядка o
+func (o SortOrder) CommentCursor(c *Comment) Cursor {
+	return Cursor{Rank: o.rank(c.Score, c.ReplyCount, c.CreatedAt), CreatedAt: c.CreatedAt, ID: c.ID}
+}
+
+// Before сообщает, идёт ли позиция a строго раньше позиции b в порядке o
+func (o SortOrder) Before(a, b Cursor) bool {
+	if o == OrderOldest {
+		return a.Less(b.CreatedAt, b.ID)
+	}
+	if a.Rank != b.Rank {
+		return a.Rank > b.Rank
+	}
+	return a.Greater(b.CreatedAt, b.ID)
+}
@@ -0,0 +1,10 @@
+package model
+
+// Page – страница keyset-пагинации
+type Page[T any] struct {
+	Items []T
+	// Есть ли элементы после последнего в Items
+	HasNextPage bool
+	// Сколько всего элементов в списке без учёта пагинации
+	TotalCount int
+}
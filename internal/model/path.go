@@ -0,0 +1,37 @@
+package model
+
+import (
+	"encoding/base64"
+	"fmt"
+)
+
+// PathSegmentLen – длина сегмента пути: 16 hex-цифр времени создания
+// в микросекундах и UUID комментария
+const PathSegmentLen = 16 + 36
+
+// PathSegment возвращает сегмент материализованного пути комментария.
+// Сегменты фиксированной длины, поэтому побайтовое сравнение путей
+// упорядочивает ответы одного родителя по (created_at, id), а родителя
+// ставит перед его ответами.
+func PathSegment(c *Comment) string {
+	return fmt.Sprintf("%016x%s", c.CreatedAt.UnixMicro(), c.ID)
+}
+
+// ChildPath возвращает путь ответа на комментарий с путём parentPath
+func ChildPath(parentPath string, c *Comment) string {
+	return parentPath + PathSegment(c)
+}
+
+// EncodePathCursor кодирует путь в непрозрачный курсор обхода ветки
+func EncodePathCursor(path string) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(path))
+}
+
+// DecodePathCursor разбирает курсор из EncodePathCursor
+func DecodePathCursor(s string) (string, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil || len(raw) == 0 || len(raw)%PathSegmentLen != 0 {
+		return "", ErrInvalidCursor
+	}
+	return string(raw), nil
+}
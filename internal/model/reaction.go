@@ -0,0 +1,32 @@
+package model
+
+import "time"
+
+// Reaction – эмодзи-реакция пользователя на пост или комментарий. Не влияет
+// на рейтинг; у пользователя одна реакция каждого вида на запись.
+type Reaction struct {
+	UserID   string `json:"userID" db:"user_id" gorm:"primaryKey;type:uuid"`
+	TargetID string `json:"targetID" db:"target_id" gorm:"primaryKey;type:uuid"` // ID поста или комментария
+	Emoji    string `json:"emoji" db:"emoji" gorm:"primaryKey;type:varchar(64)"`
+	// Пост, к которому относится запись: тема подписки reactionsChanged
+	PostID    string    `json:"postID" db:"post_id" gorm:"type:uuid;not null"`
+	CreatedAt time.Time `json:"createdAt" db:"created_at" gorm:"type:timestamp;default:CURRENT_TIMESTAMP"`
+}
+
+// ReactionCount – число реакций одного вида на запись и есть ли среди них
+// реакция текущего пользователя
+type ReactionCount struct {
+	Emoji         string
+	Count         int
+	ViewerReacted bool
+}
+
+// ReactionChange – результат добавления или снятия реакции. Changed = false,
+// если реакция уже была (или её не было). Reactions – реакции на запись после
+// изменения с точки зрения пользователя, который их менял
+type ReactionChange struct {
+	PostID    string
+	TargetID  string
+	Changed   bool
+	Reactions []*ReactionCount
+}
@@ -0,0 +1,15 @@
+package model
+
+import "time"
+
+// Revision – прежняя версия поста или комментария. Сохраняется перед каждым
+// изменением и удалением: EditorID и CreatedAt – кто и когда заменил эту версию.
+type Revision struct {
+	ID       string  `json:"id" db:"id" gorm:"primaryKey;type:uuid"`
+	EntityID string  `json:"entityID" db:"entity_id" gorm:"type:uuid;not null"` // ID поста или комментария
+	Title    *string `json:"title" db:"title" gorm:"type:varchar(50)"`          // nil у комментариев
+	Content  string  `json:"content" db:"content" gorm:"type:text;not null"`
+	EditorID string  `json:"editorID" db:"editor_id" gorm:"type:uuid;not null"`
+	// Время замены версии
+	CreatedAt time.Time `json:"createdAt" db:"created_at" gorm:"type:timestamp;not null"`
+}
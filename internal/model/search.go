@@ -0,0 +1,29 @@
+package model
+
+// SearchKind – что искать: посты, комментарии или всё вместе
+type SearchKind string
+
+const (
+	SearchAll      SearchKind = "ALL"
+	SearchPosts    SearchKind = "POST"
+	SearchComments SearchKind = "COMMENT"
+)
+
+// SearchHit – найденный пост или комментарий (заполнено одно из полей).
+// Snippet – фрагмент текста, совпадения выделены маркерами search.MarkStart
+// и search.MarkStop
+type SearchHit struct {
+	Post    *Post
+	Comment *Comment
+	Rank    float64
+	Snippet string
+}
+
+// Cursor возвращает курсор результата. Результаты идут по убыванию веса,
+// при равном весе – от новых к старым, как в ранжированных SortOrder
+func (h *SearchHit) Cursor() Cursor {
+	if h.Post != nil {
+		return Cursor{Rank: h.Rank, CreatedAt: h.Post.CreatedAt, ID: h.Post.ID}
+	}
+	return Cursor{Rank: h.Rank, CreatedAt: h.Comment.CreatedAt, ID: h.Comment.ID}
+}
@@ -0,0 +1,17 @@
+package model
+
+// CommentNode – комментарий в дереве обсуждения
+type CommentNode struct {
+	Comment  *Comment
+	Depth    int // 0 – комментарий верхнего уровня
+	Children []*CommentNode
+	// Сколько ответов не вошло в дерево из-за ограничений глубины и числа ответов
+	TruncatedReplies int
+}
+
+// CommentTree – дерево комментариев поста, ограниченное по глубине и ширине
+type CommentTree struct {
+	Roots []*CommentNode
+	// Сколько комментариев верхнего уровня не вошло в дерево
+	TruncatedRoots int
+}
@@ -0,0 +1,18 @@
+package model
+
+import "time"
+
+// Vote – голос пользователя за пост или комментарий: +1 или -1.
+// У пользователя один голос на запись, отмена голоса удаляет строку.
+type Vote struct {
+	UserID    string    `json:"userID" db:"user_id" gorm:"primaryKey;type:uuid"`
+	TargetID  string    `json:"targetID" db:"target_id" gorm:"primaryKey;type:uuid"` // ID поста или комментария
+	Value     int       `json:"value" db:"value" gorm:"type:smallint;not null"`
+	CreatedAt time.Time `json:"createdAt" db:"created_at" gorm:"type:timestamp;default:CURRENT_TIMESTAMP"`
+}
+
+// Votable – пост или комментарий, за который проголосовали (заполнено одно поле)
+type Votable struct {
+	Post    *Post
+	Comment *Comment
+}
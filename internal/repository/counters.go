@@ -0,0 +1,84 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// RecountStats – число строк, у которых пересчёт изменил счётчики
+type RecountStats struct {
+	Posts    int64
+	Comments int64
+}
+
+// RecountCounters пересчитывает comment_count постов, reply_count комментариев,
+// флаги have_comments и голоса upvotes, downvotes, score по фактическим данным. Работает в одной транзакции и
+// без таймаута запроса: на большой базе пересчёт может идти долго.
+func RecountCounters(ctx context.Context, db *sqlx.DB) (*RecountStats, error) {
+	tx, err := db.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, wrapDBError(err, "failed to begin transaction")
+	}
+	defer tx.Rollback()
+
+	// Блокируем вставку комментариев и голосов на время пересчёта, чтобы
+	// счётчики не разошлись с транзакциями, начатыми параллельно
+	if _, err := tx.ExecContext(ctx, `LOCK TABLE comments, votes IN SHARE MODE`); err != nil {
+		return nil, wrapDBError(err, "failed to lock comments and votes")
+	}
+
+	stats := &RecountStats{}
+
+	res, err := tx.ExecContext(ctx, `
+		UPDATE posts
+		SET comment_count = counts.total, have_comments = counts.total > 0,
+			upvotes = counts.up, downvotes = counts.down, score = counts.up - counts.down
+		FROM (
+			SELECT posts.id,
+				(SELECT COUNT(*) FROM comments WHERE comments.post_id = posts.id) AS total,
+				(SELECT COUNT(*) FROM votes WHERE votes.target_id = posts.id AND votes.value = 1) AS up,
+				(SELECT COUNT(*) FROM votes WHERE votes.target_id = posts.id AND votes.value = -1) AS down
+			FROM posts
+		) counts
+		WHERE posts.id = counts.id
+			AND (posts.comment_count <> counts.total OR posts.have_comments <> (counts.total > 0)
+				OR posts.upvotes <> counts.up OR posts.downvotes <> counts.down
+				OR posts.score <> counts.up - counts.down)
+	`)
+	if err != nil {
+		return nil, wrapDBError(err, "failed to recount post counters")
+	}
+	if stats.Posts, err = res.RowsAffected(); err != nil {
+		return nil, wrapDBError(err, "failed to recount post counters")
+	}
+
+	res, err = tx.ExecContext(ctx, `
+		UPDATE comments
+		SET reply_count = counts.total, have_comments = counts.total > 0,
+			upvotes = counts.up, downvotes = counts.down, score = counts.up - counts.down
+		FROM (
+			SELECT parent.id,
+				(SELECT COUNT(*) FROM comments reply WHERE reply.parent_id = parent.id) AS total,
+				(SELECT COUNT(*) FROM votes WHERE votes.target_id = parent.id AND votes.value = 1) AS up,
+				(SELECT COUNT(*) FROM votes WHERE votes.target_id = parent.id AND votes.value = -1) AS down
+			FROM comments parent
+		) counts
+		WHERE comments.id = counts.id
+			AND (comments.reply_count <> counts.total OR comments.have_comments <> (counts.total > 0)
+				OR comments.upvotes <> counts.up OR comments.downvotes <> counts.down
+				OR comments.score <> counts.up - counts.down)
+	`)
+	if err != nil {
+		return nil, wrapDBError(err, "failed to recount reply counters")
+	}
+	if stats.Comments, err = res.RowsAffected(); err != nil {
+		return nil, wrapDBError(err, "failed to recount reply counters")
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, wrapDBError(err, "failed to commit recount")
+	}
+
+	return stats, nil
+}
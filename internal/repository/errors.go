@@ -0,0 +1,168 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"net"
+	"strings"
+
+	"github.com/jackc/pgx"
+	"github.com/lib/pq"
+)
+
+// Виды ошибок хранилища. Проверяются через errors.Is:
+//
+//	if errors.Is(err, repository.ErrNotFound) { ... }
+var (
+	ErrNotFound    = errors.New("не найдено")
+	ErrConflict    = errors.New("конфликт")
+	ErrForbidden   = errors.New("доступ запрещён")
+	ErrValidation  = errors.New("некорректные данные")
+	ErrUnavailable = errors.New("хранилище недоступно")
+)
+
+// ErrCommentsDisabled – автор поста отключил комментарии. Относится к виду ErrForbidden.
+var ErrCommentsDisabled error = &Error{Kind: ErrForbidden, Message: "комментарии к посту отключены"}
+
+// ErrPostDeleted и ErrCommentDeleted – удалённые пост или комментарий нельзя
+// изменить. Относятся к виду ErrConflict.
+var (
+	ErrPostDeleted    error = &Error{Kind: ErrConflict, Message: "пост удалён"}
+	ErrCommentDeleted error = &Error{Kind: ErrConflict, Message: "комментарий удалён"}
+)
+
+// Error – ошибка хранилища определённого вида с сообщением для клиента
+type Error struct {
+	Kind    error  // один из ErrNotFound, ErrConflict, ErrForbidden, ErrValidation, ErrUnavailable
+	Message string // сообщение, которое можно показать клиенту
+	Err     error  // исходная ошибка, если есть
+}
+
+func (e *Error) Error() string {
+	if e.Err != nil {
+		return e.Message + ": " + e.Err.Error()
+	}
+	return e.Message
+}
+
+// Unwrap позволяет errors.Is находить и вид ошибки, и исходную ошибку
+func (e *Error) Unwrap() []error {
+	if e.Err != nil {
+		return []error{e.Kind, e.Err}
+	}
+	return []error{e.Kind}
+}
+
+// NotFound – сущность не найдена
+func NotFound(message string) error {
+	return &Error{Kind: ErrNotFound, Message: message}
+}
+
+// Conflict – нарушение уникальности или конкурирующее изменение
+func Conflict(message string) error {
+	return &Error{Kind: ErrConflict, Message: message}
+}
+
+// Forbidden – операция запрещена для этой сущности
+func Forbidden(message string) error {
+	return &Error{Kind: ErrForbidden, Message: message}
+}
+
+// Validation – некорректные входные данные
+func Validation(message string) error {
+	return &Error{Kind: ErrValidation, Message: message}
+}
+
+// Unavailable – хранилище временно недоступно
+func Unavailable(err error) error {
+	return &Error{Kind: ErrUnavailable, Message: "хранилище временно недоступно", Err: err}
+}
+
+// Message возвращает сообщение для клиента, если err – ошибка хранилища
+func Message(err error) (string, bool) {
+	var repoErr *Error
+	if errors.As(err, &repoErr) {
+		return repoErr.Message, true
+	}
+	return "", false
+}
+
+// pgCode возвращает SQLSTATE ошибки PostgreSQL (драйверы pgx и lib/pq)
+func pgCode(err error) string {
+	var pgxErr pgx.PgError
+	if errors.As(err, &pgxErr) {
+		return pgxErr.Code
+	}
+
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		return string(pqErr.Code)
+	}
+
+	return ""
+}
+
+// isDuplicateKeyError проверка дупликата
+func isDuplicateKeyError(err error) bool {
+	// PostgreSQL возвращает ошибку с кодом "23505" при нарушении уникальности
+	return pgCode(err) == "23505"
+}
+
+// isTooLongError распознаёт значение длиннее столбца (22001 string_data_right_truncation).
+// Обычно такие значения отсекает пакет validation, это запасная проверка.
+func isTooLongError(err error) bool {
+	return pgCode(err) == "22001"
+}
+
+// isUnavailableError распознаёт потерю соединения и перегрузку базы
+func isUnavailableError(err error) bool {
+	if errors.Is(err, driver.ErrBadConn) ||
+		errors.Is(err, sql.ErrConnDone) ||
+		errors.Is(err, pgx.ErrDeadConn) ||
+		errors.Is(err, pgx.ErrClosedPool) {
+		return true
+	}
+
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		return true
+	}
+
+	code := pgCode(err)
+	switch {
+	case strings.HasPrefix(code, "08"): // connection exception
+		return true
+	case strings.HasPrefix(code, "53"): // insufficient resources
+		return true
+	case code == "57P01", code == "57P02", code == "57P03": // сервер останавливается или недоступен
+		return true
+	}
+
+	return false
+}
+
+// wrapDBError оборачивает ошибку базы: недоступность помечается ErrUnavailable,
+// истечение дедлайна остаётся распознаваемым через errors.Is
+func wrapDBError(err error, message string) error {
+	if errors.Is(err, context.DeadlineExceeded) || errors.Is(err, context.Canceled) {
+		return &wrappedError{message: message, err: err}
+	}
+	if isUnavailableError(err) {
+		return Unavailable(&wrappedError{message: message, err: err})
+	}
+	if isTooLongError(err) {
+		return &Error{Kind: ErrValidation, Message: "значение слишком длинное", Err: &wrappedError{message: message, err: err}}
+	}
+	return &wrappedError{message: message, err: err}
+}
+
+// wrappedError – внутренняя ошибка с контекстом операции
+type wrappedError struct {
+	message string
+	err     error
+}
+
+func (e *wrappedError) Error() string { return e.message + ": " + e.err.Error() }
+func (e *wrappedError) Unwrap() error { return e.err }
@@ -2,22 +2,33 @@ package repository
 
 import (
 	"context"
-	"errors"
-	"fmt"
 	"sort"
 	"sync"
 	"time"
 
 	"github.com/22Fariz22/forum/internal/model"
+	"github.com/22Fariz22/forum/pkg/search"
+	"github.com/google/uuid"
 )
 
 type InMemoryRepository struct {
 	users         map[string]*model.User
-	posts         map[string]*model.Post      //посты по post_id(выдаем при просмотре одного поста за O(1))
-	sortedPosts   []*model.Post               //отсортированные посты по CreatedAt(выдача всех постов за О(1))
-	comments      map[string][]*model.Comment //key=post_id
-	replyComments map[string][]*model.Comment //key=parentID
-	subscribers   map[string][]chan *model.Comment
+	posts         map[string]*model.Post       //посты по post_id(выдаем при просмотре одного поста за O(1))
+	sortedPosts   []*model.Post                //посты по убыванию (created_at, id)(выдача всех постов за О(1))
+	comments      map[string][]*model.Comment  //key=post_id, по возрастанию (created_at, id)
+	replyComments map[string][]*model.Comment  //key=parentID, по возрастанию (created_at, id)
+	commentsByID  map[string]*model.Comment    //все комментарии по comment_id
+	postComments  map[string][]*model.Comment  //key=post_id, комментарии всех уровней по возрастанию (created_at, id)
+	threads       map[string][]*model.Comment  //key=post_id, комментарии всех уровней по возрастанию пути (обход в глубину)
+	revisions     map[string][]*model.Revision //key=post_id или comment_id, прежние версии от старых к новым
+	revisionsByID map[string]*model.Revision
+	textIndex     *search.Index                //посты и комментарии по словам для полнотекстового поиска
+	votes         map[string]map[string]int    //key=post_id или comment_id, голоса по user_id
+	reactions     map[string][]*model.Reaction //key=post_id или comment_id, реакции в порядке добавления
+	forums        map[string]*model.Forum
+	sortedForums  []*model.Forum               //разделы по возрастанию Position
+	bookmarks     map[string][]*model.Bookmark //key=user_id, по убыванию (created_at, target_id)
+	bookmarkedBy  map[string]map[string]bool   //key=post_id или comment_id, пользователи с закладкой
 	mu            sync.RWMutex
 }
 
@@ -28,25 +39,44 @@ func NewInMemoryRepository() Repository {
 		sortedPosts:   []*model.Post{},
 		comments:      make(map[string][]*model.Comment),
 		replyComments: make(map[string][]*model.Comment),
-		subscribers:   make(map[string][]chan *model.Comment),
+		commentsByID:  make(map[string]*model.Comment),
+		postComments:  make(map[string][]*model.Comment),
+		threads:       make(map[string][]*model.Comment),
+		revisions:     make(map[string][]*model.Revision),
+		revisionsByID: make(map[string]*model.Revision),
+		textIndex:     search.NewIndex(),
+		votes:         make(map[string]map[string]int),
+		reactions:     make(map[string][]*model.Reaction),
+		forums:        make(map[string]*model.Forum),
+		bookmarks:     make(map[string][]*model.Bookmark),
+		bookmarkedBy:  make(map[string]map[string]bool),
 	}
 }
 
 // CreateUser добавляет нового пользователя, если его еще нет
-func (r *InMemoryRepository) CreateUser(user *model.User) error {
+func (r *InMemoryRepository) CreateUser(ctx context.Context, user *model.User) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
 	r.mu.Lock()
 	defer r.mu.Unlock()
 
 	if _, exists := r.users[user.ID]; exists {
-		return errors.New("user already exists")
+		return Conflict("пользователь уже существует")
 	}
 
+	user.CreatedAt = now()
 	r.users[user.ID] = user
 	return nil
 }
 
 // GetUserByID проверяет существование пользователя
-func (r *InMemoryRepository) GetUserByID(id string) (*model.User, error) {
+func (r *InMemoryRepository) GetUserByID(ctx context.Context, id string) (*model.User, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
 	r.mu.RLock()
 	defer func() {
 		r.mu.RUnlock()
@@ -55,24 +85,27 @@ func (r *InMemoryRepository) GetUserByID(id string) (*model.User, error) {
 	user, exists := r.users[id]
 
 	if !exists {
-		fmt.Println("if !exists", exists)
-		return nil, errors.New("user not found")
+		return nil, NotFound("пользователь не найден")
 	}
 
 	return user, nil
 }
 
 // CreatePost добавляет новый пост, если автор существует
-func (r *InMemoryRepository) CreatePost(post *model.Post) error {
+func (r *InMemoryRepository) CreatePost(ctx context.Context, post *model.Post) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
 	// Проверяем существование пользователя перед созданием поста
-	_, err := r.GetUserByID(post.AuthorID)
+	_, err := r.GetUserByID(ctx, post.AuthorID)
 	if err != nil {
 		return err
 	}
 
 	r.mu.Lock()
 	defer r.mu.Unlock()
-	post.CreatedAt = time.Now()
+	post.CreatedAt = now()
 	r.posts[post.ID] = post
 
 	// Добавляем в slice
@@ -84,39 +117,93 @@ func (r *InMemoryRepository) CreatePost(post *model.Post) error {
 	// Создаём пустой список комментариев для поста
 	r.comments[post.ID] = []*model.Comment{}
 
+	r.indexPostText(post)
+
 	return nil
 }
 
-// sortPosts сортирует r.sortedPosts по CreatedAt (новые сверху)
+// indexPostText индексирует заголовок и текст поста для поиска
+func (r *InMemoryRepository) indexPostText(post *model.Post) {
+	r.textIndex.Add(post.ID,
+		search.Field{Text: post.Title, Weight: search.WeightA},
+		search.Field{Text: post.Content, Weight: search.WeightB},
+	)
+}
+
+// indexCommentText индексирует текст комментария для поиска
+func (r *InMemoryRepository) indexCommentText(comment *model.Comment) {
+	r.textIndex.Add(comment.ID, search.Field{Text: comment.Content, Weight: search.WeightB})
+}
+
+// sortPosts сортирует r.sortedPosts по (CreatedAt, ID) (новые сверху).
+// Сортируется копия: страницы, выданные раньше, не должны меняться
 func (r *InMemoryRepository) sortPosts() {
-	sort.Slice(r.sortedPosts, func(i, j int) bool {
-		return r.sortedPosts[i].CreatedAt.After(r.sortedPosts[j].CreatedAt)
+	sorted := make([]*model.Post, len(r.sortedPosts))
+	copy(sorted, r.sortedPosts)
+	sort.Slice(sorted, func(i, j int) bool {
+		return model.PostCursorOf(sorted[j]).Less(sorted[i].CreatedAt, sorted[i].ID)
 	})
+	r.sortedPosts = sorted
+}
+
+// replacePost подменяет пост в индексах его изменённой копией. Выданные
+// читателям посты не меняются. Вызывается под блокировкой на запись
+func (r *InMemoryRepository) replacePost(post *model.Post) {
+	old := r.posts[post.ID]
+	r.posts[post.ID] = post
+	r.sortedPosts = replaced(r.sortedPosts, old, post)
+}
+
+// replaceComment подменяет комментарий в индексах его изменённой копией.
+// Вызывается под блокировкой на запись
+func (r *InMemoryRepository) replaceComment(comment *model.Comment) {
+	old := r.commentsByID[comment.ID]
+	r.commentsByID[comment.ID] = comment
+
+	if comment.ParentID == nil {
+		r.comments[comment.PostID] = replaced(r.comments[comment.PostID], old, comment)
+	} else {
+		r.replyComments[*comment.ParentID] = replaced(r.replyComments[*comment.ParentID], old, comment)
+	}
+	r.postComments[comment.PostID] = replaced(r.postComments[comment.PostID], old, comment)
+	r.threads[comment.PostID] = replaced(r.threads[comment.PostID], old, comment)
 }
 
 // GetPosts возвращает все посты
-func (r *InMemoryRepository) GetPosts(offset int32, limit int32) ([]*model.Post, error) {
+func (r *InMemoryRepository) GetPosts(ctx context.Context, order model.SortOrder, filter model.PostFilter, offset int32, limit int32) ([]*model.Post, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
 	r.mu.RLock()
 	defer r.mu.RUnlock()
 
 	// Проверяем границы пагинации
 	if offset < 0 || limit <= 0 {
-		return nil, errors.New("неверные параметры пагинации")
+		return nil, Validation("неверные параметры пагинации")
 	}
 
 	// Ограничиваем список постов
+	list := sortedBy(r.filteredPosts(filter), model.OrderNewest, order, order.PostCursor)
 	start := int(offset)
+	if start > len(list) {
+		start = len(list)
+	}
 	end := start + int(limit)
-	if end > len(r.sortedPosts) {
-		end = len(r.sortedPosts)
+	if end > len(list) {
+		end = len(list)
 	}
 
 	// Возвращаем срез постов
-	return r.sortedPosts[offset:end], nil
+	return list[start:end], nil
 }
 
 // GetPostByID возвращает пост по ID
-func (r *InMemoryRepository) GetPostByID(id string) (*model.Post, error) {
+func (r *InMemoryRepository) GetPostByID(ctx context.Context, id string) (*model.Post, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
 	r.mu.RLock()
 	defer r.mu.RUnlock()
 
@@ -124,27 +211,156 @@ func (r *InMemoryRepository) GetPostByID(id string) (*model.Post, error) {
 		return post, nil
 	}
 
-	return nil, errors.New("пост не найден")
+	return nil, NotFound("пост не найден")
+}
+
+// SetCommentsEnabled включает или отключает комментарии к посту
+func (r *InMemoryRepository) SetCommentsEnabled(ctx context.Context, postID string, enabled bool) (*model.Post, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	post, exists := r.posts[postID]
+	if !exists {
+		return nil, NotFound("пост не найден")
+	}
+	if post.DeletedAt != nil {
+		return nil, ErrPostDeleted
+	}
+
+	updated := *post
+	updated.AllowComments = enabled
+	r.replacePost(&updated)
+
+	return &updated, nil
+}
+
+// UpdatePost меняет заголовок и/или текст поста. nil-поля не меняются
+func (r *InMemoryRepository) UpdatePost(ctx context.Context, postID, editorID string, title, content *string) (*model.Post, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	post, exists := r.posts[postID]
+	if !exists {
+		return nil, NotFound("пост не найден")
+	}
+	if post.DeletedAt != nil {
+		return nil, ErrPostDeleted
+	}
+
+	editedAt := now()
+	r.addRevision(post.ID, &post.Title, post.Content, editorID, editedAt)
+
+	updated := *post
+	if title != nil {
+		updated.Title = *title
+	}
+	if content != nil {
+		updated.Content = *content
+	}
+	updated.EditedAt = &editedAt
+	r.replacePost(&updated)
+	r.indexPostText(&updated)
+
+	return &updated, nil
+}
+
+// DeletePost мягко удаляет пост: он убирается из ленты, а по ID
+// возвращается заглушка с закрытыми комментариями
+func (r *InMemoryRepository) DeletePost(ctx context.Context, postID, editorID string) (*model.Post, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	post, exists := r.posts[postID]
+	if !exists {
+		return nil, NotFound("пост не найден")
+	}
+	if post.DeletedAt != nil {
+		return post, nil
+	}
+
+	deletedAt := now()
+	r.addRevision(post.ID, &post.Title, post.Content, editorID, deletedAt)
+
+	// Заглушка – копия: пост мог уже попасть в выданные страницы
+	deleted := *post
+	deleted.Title = model.DeletedContent
+	deleted.Content = model.DeletedContent
+	deleted.AllowComments = false
+	deleted.DeletedAt = &deletedAt
+	r.posts[postID] = &deleted
+	r.textIndex.Remove(post.ID)
+	r.removeBookmarks(post.ID)
+	// Комментарии удалённого поста закрыты, закладки на них тоже удаляются
+	for _, comment := range r.postComments[postID] {
+		r.removeBookmarks(comment.ID)
+	}
+
+	// Новый срез: страницы, выданные раньше, не должны меняться
+	sortedPosts := make([]*model.Post, 0, len(r.sortedPosts))
+	for _, p := range r.sortedPosts {
+		if p.ID != postID {
+			sortedPosts = append(sortedPosts, p)
+		}
+	}
+	r.sortedPosts = sortedPosts
+
+	return &deleted, nil
+}
+
+// checkCommentsAllowed проверяет, что пост существует и принимает комментарии.
+// Вызывается под блокировкой.
+func (r *InMemoryRepository) checkCommentsAllowed(postID string) error {
+	post, exists := r.posts[postID]
+	if !exists {
+		return NotFound("пост не найден")
+	}
+	if !post.AllowComments {
+		return ErrCommentsDisabled
+	}
+	return nil
 }
 
 // CreateCommentOnPost добавляет комментарий к посту
 func (r *InMemoryRepository) CreateCommentOnPost(ctx context.Context, comment *model.Comment) (*model.Comment, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
 	r.mu.Lock()
 	defer r.mu.Unlock()
 
 	// Проверяем, является ли комментарий верхнеуровневым
 	if comment.ParentID == nil {
+		if err := r.checkCommentsAllowed(comment.PostID); err != nil {
+			return nil, err
+		}
+
 		// Инициализируем массив комментариев для поста, если его нет
 		if _, ok := r.comments[comment.PostID]; !ok {
 			r.comments[comment.PostID] = []*model.Comment{}
 		}
 
 		// Добавляем комментарий
-		comment.CreatedAt = time.Now()
-		r.comments[comment.PostID] = append(r.comments[comment.PostID], comment)
+		comment.CreatedAt = now()
+		comment.Path = model.PathSegment(comment)
+		comment.Depth = 0
+		r.comments[comment.PostID] = insertByCursor(r.comments[comment.PostID], comment)
 
 		// Инициализируем массив для вложенных комментариев
 		r.replyComments[comment.ID] = []*model.Comment{}
+		r.indexComment(comment)
 
 		return comment, nil
 	}
@@ -152,96 +368,390 @@ func (r *InMemoryRepository) CreateCommentOnPost(ctx context.Context, comment *m
 	// Проверяем, существует ли родительский комментарий
 	parentID := *comment.ParentID
 
-	replies, ok := r.replyComments[parentID]
+	parent, ok := r.commentsByID[parentID]
 	if !ok {
-		fmt.Println("Родительский комментарий не найден:", parentID)
-		return nil, errors.New("родительский комментарий не найден")
+		return nil, NotFound("родительский комментарий не найден")
+	}
+
+	if err := r.checkCommentsAllowed(parent.PostID); err != nil {
+		return nil, err
 	}
 
-	// Добавляем время создания
-	comment.CreatedAt = time.Now()
+	// Добавляем время создания и путь в ветке
+	comment.CreatedAt = now()
+	comment.Path = model.ChildPath(parent.Path, comment)
+	comment.Depth = parent.Depth + 1
 
 	// Добавляем комментарий в список ответов
-	r.replyComments[parentID] = append(replies, comment)
+	r.replyComments[parentID] = insertByCursor(r.replyComments[parentID], comment)
 
-	// Сортируем вложенные комментарии по времени (новые сверху)
-	sortCommentsByCreatedAt(r.replyComments[parentID])
+	r.replyComments[comment.ID] = []*model.Comment{}
+	r.indexComment(comment)
 
 	return comment, nil
 }
 
-// sortCommentsByCreatedAt сортирует комментарии по времени (новые сверху)
-func sortCommentsByCreatedAt(comments []*model.Comment) {
-	sort.Slice(comments, func(i, j int) bool {
-		return comments[i].CreatedAt.After(comments[j].CreatedAt)
-	})
-}
-
 // ReplyToComment добавляет ответ на комментарий
 func (r *InMemoryRepository) ReplyToComment(ctx context.Context, comment *model.Comment) (*model.Comment, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
 	r.mu.Lock()
 	defer r.mu.Unlock()
 
 	// Проверяем, указан ли parentID
 	if comment.ParentID == nil {
-		return nil, errors.New("parentID не может быть nil")
+		return nil, Validation("не указан родительский комментарий")
 	}
 
 	parentID := *comment.ParentID
 
-	// Проверяем, существует ли родительский комментарий в `replyComments`
-	if _, exists := r.replyComments[parentID]; !exists {
-		return nil, errors.New("родительский комментарий не найден")
+	// Проверяем, существует ли родительский комментарий любого уровня
+	parent, exists := r.commentsByID[parentID]
+	if !exists {
+		return nil, NotFound("родительский комментарий не найден")
+	}
+
+	// Ответ всегда относится к посту родительского комментария
+	comment.PostID = parent.PostID
+
+	if err := r.checkCommentsAllowed(comment.PostID); err != nil {
+		return nil, err
 	}
 
-	// Добавляем время создания
-	comment.CreatedAt = time.Now()
+	// Добавляем время создания и путь в ветке
+	comment.CreatedAt = now()
+	comment.Path = model.ChildPath(parent.Path, comment)
+	comment.Depth = parent.Depth + 1
 
 	// Добавляем комментарий в список ответов
-	r.replyComments[parentID] = append(r.replyComments[parentID], comment)
+	r.replyComments[parentID] = insertByCursor(r.replyComments[parentID], comment)
 
-	// Сортируем вложенные комментарии по времени (новые сверху)
-	sortCommentsByCreatedAt(r.replyComments[parentID])
+	// На ответ тоже можно ответить
+	r.replyComments[comment.ID] = []*model.Comment{}
+	r.indexComment(comment)
 
 	return comment, nil
 }
 
-// NotifySubscribers отправляет новый комментарий подписчикам
-func (r *InMemoryRepository) NotifySubscribers(postID string, comment *model.Comment) {
-	r.mu.RLock()
-	defer r.mu.RUnlock()
+// indexComment добавляет комментарий в индексы по ID и по посту и
+// обновляет счётчики поста и родителя. Вызывается под блокировкой на запись.
+func (r *InMemoryRepository) indexComment(comment *model.Comment) {
+	r.commentsByID[comment.ID] = comment
+	r.indexCommentText(comment)
 
-	if chans, exists := r.subscribers[postID]; exists {
-		for _, ch := range chans {
-			select {
-			case ch <- comment: // Отправляем комментарий в канал
-			default:
-				fmt.Println("Канал подписчика переполнен, пропускаем уведомление")
-			}
+	if post, ok := r.posts[comment.PostID]; ok {
+		updated := *post
+		updated.CommentCount++
+		updated.HaveComments = true
+		r.replacePost(&updated)
+	}
+	if comment.ParentID != nil {
+		if parent, ok := r.commentsByID[*comment.ParentID]; ok {
+			updated := *parent
+			updated.ReplyCount++
+			updated.HaveComments = true
+			r.replaceComment(&updated)
 		}
 	}
+	r.postComments[comment.PostID] = insertByCursor(r.postComments[comment.PostID], comment)
+
+	thread := r.threads[comment.PostID]
+	i := sort.Search(len(thread), func(i int) bool {
+		return thread[i].Path > comment.Path
+	})
+	r.threads[comment.PostID] = insertAt(thread, i, comment)
+}
+
+// UpdateComment меняет текст комментария
+func (r *InMemoryRepository) UpdateComment(ctx context.Context, commentID, editorID, content string) (*model.Comment, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	comment, exists := r.commentsByID[commentID]
+	if !exists {
+		return nil, NotFound("комментарий не найден")
+	}
+	if comment.DeletedAt != nil {
+		return nil, ErrCommentDeleted
+	}
+
+	editedAt := now()
+	r.addRevision(comment.ID, nil, comment.Content, editorID, editedAt)
+
+	updated := *comment
+	updated.Content = content
+	updated.EditedAt = &editedAt
+	r.replaceComment(&updated)
+	r.indexCommentText(&updated)
+
+	return &updated, nil
 }
 
-func (r *InMemoryRepository) SubscribeToComments(postID string) <-chan *model.Comment {
+// DeleteComment удаляет комментарий. Комментарий с ответами остаётся в ветке
+// заглушкой, комментарий без ответов удаляется вместе со своими счётчиками.
+func (r *InMemoryRepository) DeleteComment(ctx context.Context, commentID, editorID string) (*model.Comment, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
 	r.mu.Lock()
 	defer r.mu.Unlock()
 
-	ch := make(chan *model.Comment, 1)
-	r.subscribers[postID] = append(r.subscribers[postID], ch)
-	return ch
+	comment, exists := r.commentsByID[commentID]
+	if !exists {
+		return nil, NotFound("комментарий не найден")
+	}
+	if comment.DeletedAt != nil {
+		return comment, nil
+	}
+
+	deletedAt := now()
+
+	// Удалённый комментарий мог уже попасть в выданные страницы,
+	// поэтому заглушка – копия
+	deleted := *comment
+	deleted.Content = model.DeletedContent
+	deleted.DeletedAt = &deletedAt
+
+	if comment.ReplyCount > 0 {
+		r.addRevision(comment.ID, nil, comment.Content, editorID, deletedAt)
+		r.replaceComment(&deleted)
+		r.textIndex.Remove(comment.ID)
+		r.removeBookmarks(comment.ID)
+		return &deleted, nil
+	}
+
+	r.unindexComment(comment)
+
+	return &deleted, nil
+}
+
+// unindexComment убирает комментарий без ответов из всех индексов и
+// уменьшает счётчики поста и родителя. Вызывается под блокировкой на запись.
+func (r *InMemoryRepository) unindexComment(comment *model.Comment) {
+	delete(r.commentsByID, comment.ID)
+	delete(r.replyComments, comment.ID)
+	r.textIndex.Remove(comment.ID)
+
+	for _, rev := range r.revisions[comment.ID] {
+		delete(r.revisionsByID, rev.ID)
+	}
+	delete(r.revisions, comment.ID)
+	delete(r.votes, comment.ID)
+	delete(r.reactions, comment.ID)
+	r.removeBookmarks(comment.ID)
+
+	if post, ok := r.posts[comment.PostID]; ok {
+		updated := *post
+		updated.CommentCount--
+		updated.HaveComments = updated.CommentCount > 0
+		r.replacePost(&updated)
+	}
+
+	if comment.ParentID == nil {
+		r.comments[comment.PostID] = without(r.comments[comment.PostID], comment)
+	} else {
+		r.replyComments[*comment.ParentID] = without(r.replyComments[*comment.ParentID], comment)
+	}
+	r.postComments[comment.PostID] = without(r.postComments[comment.PostID], comment)
+	r.threads[comment.PostID] = without(r.threads[comment.PostID], comment)
+
+	if comment.ParentID != nil {
+		if parent, ok := r.commentsByID[*comment.ParentID]; ok {
+			updated := *parent
+			updated.ReplyCount--
+			updated.HaveComments = updated.ReplyCount > 0
+			r.replaceComment(&updated)
+		}
+	}
+}
+
+// addRevision сохраняет прежнюю версию поста или комментария.
+// Вызывается под блокировкой на запись.
+func (r *InMemoryRepository) addRevision(entityID string, title *string, content, editorID string, at time.Time) {
+	rev := &model.Revision{
+		ID:        uuid.New().String(),
+		EntityID:  entityID,
+		Content:   content,
+		EditorID:  editorID,
+		CreatedAt: at,
+	}
+	if title != nil {
+		t := *title
+		rev.Title = &t
+	}
+
+	r.revisions[entityID] = append(r.revisions[entityID], rev)
+	r.revisionsByID[rev.ID] = rev
+}
+
+// GetRevisions возвращает прежние версии поста или комментария от старых к новым
+func (r *InMemoryRepository) GetRevisions(ctx context.Context, entityID string) ([]*model.Revision, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	result := make([]*model.Revision, len(r.revisions[entityID]))
+	copy(result, r.revisions[entityID])
+
+	return result, nil
+}
+
+// GetRevisionByID возвращает ревизию по ID
+func (r *InMemoryRepository) GetRevisionByID(ctx context.Context, id string) (*model.Revision, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if rev, exists := r.revisionsByID[id]; exists {
+		return rev, nil
+	}
+
+	return nil, NotFound("ревизия не найдена")
+}
+
+// without возвращает новый список без комментария. Исходный срез не меняется:
+// его части могли быть выданы читателям.
+func without(list []*model.Comment, comment *model.Comment) []*model.Comment {
+	result := make([]*model.Comment, 0, len(list))
+	for _, c := range list {
+		if c != comment {
+			result = append(result, c)
+		}
+	}
+	return result
+}
+
+// insertByCursor вставляет комментарий в список, упорядоченный
+// по возрастанию (created_at, id), и возвращает новый список
+func insertByCursor(list []*model.Comment, comment *model.Comment) []*model.Comment {
+	i := sort.Search(len(list), func(i int) bool {
+		return model.CursorOf(comment).Less(list[i].CreatedAt, list[i].ID)
+	})
+	return insertAt(list, i, comment)
+}
+
+// insertAt возвращает новый список с item на позиции i. Исходный срез не меняется
+func insertAt[T any](list []T, i int, item T) []T {
+	result := make([]T, 0, len(list)+1)
+	result = append(result, list[:i]...)
+	result = append(result, item)
+	return append(result, list[i:]...)
+}
+
+// replaced возвращает копию списка, в которой old заменён на updated.
+// Если old в списке нет, список возвращается как есть
+func replaced[T comparable](list []T, old, updated T) []T {
+	for i, item := range list {
+		if item == old {
+			result := make([]T, len(list))
+			copy(result, list)
+			result[i] = updated
+			return result
+		}
+	}
+	return list
+}
+
+// GetCommentsAfter возвращает комментарии поста всех уровней, созданные после курсора,
+// по возрастанию (created_at, id)
+func (r *InMemoryRepository) GetCommentsAfter(ctx context.Context, postID string, after model.Cursor, limit int) ([]*model.Comment, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	list := r.postComments[postID]
+
+	// Первый комментарий, который строго позже курсора
+	start := sort.Search(len(list), func(i int) bool {
+		return after.Less(list[i].CreatedAt, list[i].ID)
+	})
+
+	end := start + limit
+	if end > len(list) {
+		end = len(list)
+	}
+
+	result := make([]*model.Comment, end-start)
+	copy(result, list[start:end])
+
+	return result, nil
+}
+
+// GetCommentsEditedAfter возвращает комментарии поста, изменённые после курсора
+func (r *InMemoryRepository) GetCommentsEditedAfter(ctx context.Context, postID string, after model.Cursor, limit int) ([]*model.Comment, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	// Правки не индексируются: подписчиков на переподключении немного
+	var result []*model.Comment
+	for _, c := range r.postComments[postID] {
+		if c.EditedAt != nil && c.DeletedAt == nil && after.Less(*c.EditedAt, c.ID) {
+			result = append(result, c)
+		}
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		return model.OrderOldest.Before(model.EditCursorOf(result[i]), model.EditCursorOf(result[j]))
+	})
+
+	if len(result) > limit {
+		result = result[:limit]
+	}
+
+	return result, nil
+}
+
+// GetCommentByID возвращает комментарий по ID
+func (r *InMemoryRepository) GetCommentByID(ctx context.Context, id string) (*model.Comment, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if comment, exists := r.commentsByID[id]; exists {
+		return comment, nil
+	}
+
+	return nil, NotFound("комментарий не найден")
 }
 
 // GetCommentsByPostID получает комментарии верхнего уровня
-func (r *InMemoryRepository) GetCommentsByPostID(postID string, offset, limit int) ([]*model.Comment, error) {
+func (r *InMemoryRepository) GetCommentsByPostID(ctx context.Context, postID string, order model.SortOrder, offset, limit int) ([]*model.Comment, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
 	r.mu.RLock()
 	defer r.mu.RUnlock()
 
 	// Проверяем, есть ли комментарии у поста
 	comments, exists := r.comments[postID]
 	if !exists || len(comments) == 0 {
-		fmt.Println("if !exists || len(comments) == 0 ", !exists, len(comments) == 0)
 		return []*model.Comment{}, nil
 	}
+	comments = sortedBy(comments, model.OrderOldest, order, order.CommentCursor)
 
 	// Проверяем, что offset не выходит за границы
 	if offset < 0 || offset >= len(comments) {
@@ -250,7 +760,6 @@ func (r *InMemoryRepository) GetCommentsByPostID(postID string, offset, limit in
 
 	end := offset + limit
 	if end > len(comments) {
-		fmt.Println("		end = len(comments)")
 		end = len(comments)
 	}
 
@@ -258,28 +767,739 @@ func (r *InMemoryRepository) GetCommentsByPostID(postID string, offset, limit in
 }
 
 // GetReplies возвращает вложенные комментарии по parentID
-func (r *InMemoryRepository) GetReplies(parentID string, offset, limit int) ([]*model.Comment, error) {
+func (r *InMemoryRepository) GetReplies(ctx context.Context, parentID string, order model.SortOrder, offset, limit int) ([]*model.Comment, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
 	r.mu.RLock()
 	defer r.mu.RUnlock()
 
 	replies, exists := r.replyComments[parentID]
 	if !exists || len(replies) == 0 {
-		fmt.Println("	Нет вложенных комментариев для parentID:", parentID)
 		return []*model.Comment{}, nil
 	}
+	replies = sortedBy(replies, model.OrderOldest, order, order.CommentCursor)
 
 	// Проверяем, что offset не выходит за границы
 	if offset < 0 || offset >= len(replies) {
-		fmt.Println("		Offset выходит за пределы списка. Offset:", offset, "Length of replies:", len(replies))
 		return []*model.Comment{}, nil
 	}
 
 	// Определяем границы среза с учётом лимита
 	end := offset + limit
 	if end > len(replies) {
-		fmt.Println("	Конечный индекс превышает длину списка, устанавливаем end:", len(replies))
 		end = len(replies)
 	}
 
 	return replies[offset:end], nil
 }
+
+// GetPostsPage возвращает страницу постов в порядке order
+func (r *InMemoryRepository) GetPostsPage(ctx context.Context, order model.SortOrder, filter model.PostFilter, first int, after *model.Cursor) (*model.Page[*model.Post], error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return keysetPage(r.filteredPosts(filter), model.OrderNewest, order, order.PostCursor, first, after), nil
+}
+
+// filteredPosts возвращает посты ленты, прошедшие фильтр, в порядке r.sortedPosts.
+// Вызывается под блокировкой
+func (r *InMemoryRepository) filteredPosts(filter model.PostFilter) []*model.Post {
+	if filter.ForumID == "" && len(filter.Tags) == 0 {
+		return r.sortedPosts
+	}
+
+	posts := []*model.Post{}
+	for _, post := range r.sortedPosts {
+		if filter.Match(post) {
+			posts = append(posts, post)
+		}
+	}
+	return posts
+}
+
+// GetCommentsPage возвращает страницу комментариев верхнего уровня в порядке order
+func (r *InMemoryRepository) GetCommentsPage(ctx context.Context, postID string, order model.SortOrder, first int, after *model.Cursor) (*model.Page[*model.Comment], error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return keysetPage(r.comments[postID], model.OrderOldest, order, order.CommentCursor, first, after), nil
+}
+
+// GetRepliesPage возвращает страницу ответов на комментарий в порядке order
+func (r *InMemoryRepository) GetRepliesPage(ctx context.Context, parentID string, order model.SortOrder, first int, after *model.Cursor) (*model.Page[*model.Comment], error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return keysetPage(r.replyComments[parentID], model.OrderOldest, order, order.CommentCursor, first, after), nil
+}
+
+// sortedBy возвращает список в порядке order. Хранимые списки уже упорядочены
+// по native и отдаются как есть, для остальных порядков сортируется копия
+func sortedBy[T any](list []T, native, order model.SortOrder, cursorOf func(T) model.Cursor) []T {
+	if order == native {
+		return list
+	}
+
+	sorted := make([]T, len(list))
+	copy(sorted, list)
+	sort.Slice(sorted, func(i, j int) bool {
+		return order.Before(cursorOf(sorted[i]), cursorOf(sorted[j]))
+	})
+
+	return sorted
+}
+
+// keysetPage упорядочивает список и ищет курсор двоичным поиском
+func keysetPage[T any](list []T, native, order model.SortOrder, cursorOf func(T) model.Cursor, first int, after *model.Cursor) *model.Page[T] {
+	list = sortedBy(list, native, order, cursorOf)
+
+	start := 0
+	if after != nil {
+		start = sort.Search(len(list), func(i int) bool {
+			return order.Before(*after, cursorOf(list[i]))
+		})
+	}
+
+	return pageOf(list, start, first)
+}
+
+// pageOf копирует не больше first элементов списка начиная со start
+func pageOf[T any](list []T, start, first int) *model.Page[T] {
+	end := start + first
+	if end > len(list) {
+		end = len(list)
+	}
+
+	items := make([]T, end-start)
+	copy(items, list[start:end])
+
+	return &model.Page[T]{
+		Items:       items,
+		HasNextPage: end < len(list),
+		TotalCount:  len(list),
+	}
+}
+
+// GetCommentsByPostIDs возвращает страницы комментариев верхнего уровня для нескольких постов
+func (r *InMemoryRepository) GetCommentsByPostIDs(ctx context.Context, postIDs []string, order model.SortOrder, offset, limit int) (map[string][]*model.Comment, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	result := make(map[string][]*model.Comment, len(postIDs))
+	for _, id := range postIDs {
+		result[id] = offsetPage(sortedBy(r.comments[id], model.OrderOldest, order, order.CommentCursor), offset, limit)
+	}
+
+	return result, nil
+}
+
+// GetRepliesByParentIDs возвращает страницы ответов для нескольких комментариев
+func (r *InMemoryRepository) GetRepliesByParentIDs(ctx context.Context, parentIDs []string, order model.SortOrder, offset, limit int) (map[string][]*model.Comment, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	result := make(map[string][]*model.Comment, len(parentIDs))
+	for _, id := range parentIDs {
+		result[id] = offsetPage(sortedBy(r.replyComments[id], model.OrderOldest, order, order.CommentCursor), offset, limit)
+	}
+
+	return result, nil
+}
+
+// offsetPage копирует срез [offset, offset+limit) списка
+func offsetPage(list []*model.Comment, offset, limit int) []*model.Comment {
+	if offset < 0 || limit <= 0 || offset >= len(list) {
+		return []*model.Comment{}
+	}
+	return pageOf(list, offset, limit).Items
+}
+
+// GetCommentTree обходит replyComments в глубину от комментариев верхнего уровня
+func (r *InMemoryRepository) GetCommentTree(ctx context.Context, postID string, maxDepth, perLevelLimit int) (*model.CommentTree, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	roots := r.comments[postID]
+	tree := &model.CommentTree{
+		Roots:          r.commentNodes(roots, 0, maxDepth, perLevelLimit),
+		TruncatedRoots: max(len(roots)-perLevelLimit, 0),
+	}
+
+	return tree, nil
+}
+
+// commentNodes строит узлы для первых perLevelLimit комментариев списка.
+// Вызывается под блокировкой на чтение.
+func (r *InMemoryRepository) commentNodes(comments []*model.Comment, depth, maxDepth, perLevelLimit int) []*model.CommentNode {
+	if len(comments) > perLevelLimit {
+		comments = comments[:perLevelLimit]
+	}
+
+	nodes := make([]*model.CommentNode, 0, len(comments))
+	for _, c := range comments {
+		replies := r.replyComments[c.ID]
+		node := &model.CommentNode{Comment: c, Depth: depth}
+
+		if depth < maxDepth {
+			node.Children = r.commentNodes(replies, depth+1, maxDepth, perLevelLimit)
+		}
+		node.TruncatedReplies = len(replies) - len(node.Children)
+
+		nodes = append(nodes, node)
+	}
+
+	return nodes
+}
+
+// GetThreadPage возвращает страницу комментариев поста в порядке обхода ветки в глубину
+func (r *InMemoryRepository) GetThreadPage(ctx context.Context, postID string, first int, after string) (*model.Page[*model.Comment], error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	thread := r.threads[postID]
+	start := sort.Search(len(thread), func(i int) bool {
+		return thread[i].Path > after
+	})
+
+	return pageOf(thread, start, first), nil
+}
+
+// Search ищет посты и комментарии по инвертированному индексу. Порядок и
+// курсоры – как у ранжированных SortOrder: по убыванию веса, затем от новых к старым
+func (r *InMemoryRepository) Search(ctx context.Context, query string, kind model.SearchKind, first int, after *model.Cursor) (*model.Page[*model.SearchHit], error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	q := search.ParseQuery(query)
+	if q.Empty() {
+		return &model.Page[*model.SearchHit]{Items: []*model.SearchHit{}}, nil
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var hits []*model.SearchHit
+	for _, m := range r.textIndex.Search(q) {
+		if post, ok := r.posts[m.ID]; ok {
+			if kind != model.SearchComments {
+				hits = append(hits, &model.SearchHit{Post: post, Rank: m.Rank})
+			}
+			continue
+		}
+
+		comment, ok := r.commentsByID[m.ID]
+		if !ok || kind == model.SearchPosts {
+			continue
+		}
+		// Комментарии удалённого поста остаются в индексе, но не ищутся
+		if post, ok := r.posts[comment.PostID]; !ok || post.DeletedAt != nil {
+			continue
+		}
+		hits = append(hits, &model.SearchHit{Comment: comment, Rank: m.Rank})
+	}
+
+	page := keysetPage(hits, "", model.OrderTop, (*model.SearchHit).Cursor, first, after)
+
+	// Фрагменты строятся только для выданной страницы
+	for _, h := range page.Items {
+		if h.Post != nil {
+			h.Snippet = search.Highlight(h.Post.Title+"\n"+h.Post.Content, q, search.SnippetWords)
+		} else {
+			h.Snippet = search.Highlight(h.Comment.Content, q, search.SnippetWords)
+		}
+	}
+
+	return page, nil
+}
+
+// Vote ставит или отменяет голос. Голос и счётчики меняются под одной
+// блокировкой на запись
+func (r *InMemoryRepository) Vote(ctx context.Context, userID, targetID string, value int) (*model.Votable, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var (
+		post    *model.Post
+		comment *model.Comment
+	)
+	if p, ok := r.posts[targetID]; ok {
+		if p.DeletedAt != nil {
+			return nil, ErrPostDeleted
+		}
+		post = p
+	} else if c, ok := r.commentsByID[targetID]; ok {
+		if c.DeletedAt != nil {
+			return nil, ErrCommentDeleted
+		}
+		comment = c
+	} else {
+		return nil, NotFound("пост или комментарий не найден")
+	}
+
+	votes := r.votes[targetID]
+	if votes == nil {
+		votes = make(map[string]int)
+		r.votes[targetID] = votes
+	}
+
+	up, down := voteDelta(votes[userID], value)
+	if value == 0 {
+		delete(votes, userID)
+	} else {
+		votes[userID] = value
+	}
+
+	// Счётчики меняются в копии: выданные читателям записи не меняются
+	if post != nil {
+		updated := *post
+		updated.Upvotes += up
+		updated.Downvotes += down
+		updated.Score += up - down
+		r.replacePost(&updated)
+		return &model.Votable{Post: &updated}, nil
+	}
+
+	updated := *comment
+	updated.Upvotes += up
+	updated.Downvotes += down
+	updated.Score += up - down
+	r.replaceComment(&updated)
+	return &model.Votable{Comment: &updated}, nil
+}
+
+// GetVotes возвращает голоса пользователя за записи targetIDs
+func (r *InMemoryRepository) GetVotes(ctx context.Context, userID string, targetIDs []string) (map[string]int, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	votes := make(map[string]int, len(targetIDs))
+	for _, id := range targetIDs {
+		if value, ok := r.votes[id][userID]; ok {
+			votes[id] = value
+		}
+	}
+
+	return votes, nil
+}
+
+// AddReaction добавляет реакцию пользователя, если её ещё нет
+func (r *InMemoryRepository) AddReaction(ctx context.Context, userID, targetID, emoji string) (*model.ReactionChange, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	postID, err := r.activeTarget(targetID)
+	if err != nil {
+		return nil, err
+	}
+
+	change := &model.ReactionChange{PostID: postID, TargetID: targetID}
+	if indexOfReaction(r.reactions[targetID], userID, emoji) < 0 {
+		r.reactions[targetID] = append(r.reactions[targetID], &model.Reaction{
+			UserID:    userID,
+			TargetID:  targetID,
+			Emoji:     emoji,
+			PostID:    postID,
+			CreatedAt: now(),
+		})
+		change.Changed = true
+	}
+	change.Reactions = countReactions(r.reactions[targetID], userID)
+
+	return change, nil
+}
+
+// RemoveReaction снимает реакцию пользователя, если она есть
+func (r *InMemoryRepository) RemoveReaction(ctx context.Context, userID, targetID, emoji string) (*model.ReactionChange, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	postID, err := r.activeTarget(targetID)
+	if err != nil {
+		return nil, err
+	}
+
+	change := &model.ReactionChange{PostID: postID, TargetID: targetID}
+	list := r.reactions[targetID]
+	if i := indexOfReaction(list, userID, emoji); i >= 0 {
+		list = append(list[:i:i], list[i+1:]...)
+		if len(list) == 0 {
+			delete(r.reactions, targetID)
+		} else {
+			r.reactions[targetID] = list
+		}
+		change.Changed = true
+	}
+	change.Reactions = countReactions(list, userID)
+
+	return change, nil
+}
+
+// activeTarget проверяет, что пост или комментарий существует и не удалён
+// (на него можно реагировать и сохранять в закладки), и возвращает ID поста.
+// Вызывается под блокировкой
+func (r *InMemoryRepository) activeTarget(targetID string) (string, error) {
+	if post, ok := r.posts[targetID]; ok {
+		if post.DeletedAt != nil {
+			return "", ErrPostDeleted
+		}
+		return post.ID, nil
+	}
+	if comment, ok := r.commentsByID[targetID]; ok {
+		if comment.DeletedAt != nil {
+			return "", ErrCommentDeleted
+		}
+		return comment.PostID, nil
+	}
+	return "", NotFound("пост или комментарий не найден")
+}
+
+// GetReactions возвращает реакции на записи targetIDs
+func (r *InMemoryRepository) GetReactions(ctx context.Context, userID string, targetIDs []string) (map[string][]*model.ReactionCount, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	reactions := make(map[string][]*model.ReactionCount, len(targetIDs))
+	for _, id := range targetIDs {
+		if list := r.reactions[id]; len(list) > 0 {
+			reactions[id] = countReactions(list, userID)
+		}
+	}
+
+	return reactions, nil
+}
+
+func indexOfReaction(list []*model.Reaction, userID, emoji string) int {
+	for i, reaction := range list {
+		if reaction.UserID == userID && reaction.Emoji == emoji {
+			return i
+		}
+	}
+	return -1
+}
+
+// countReactions считает реакции по видам в порядке первой реакции каждого вида
+func countReactions(list []*model.Reaction, userID string) []*model.ReactionCount {
+	counts := []*model.ReactionCount{}
+	byEmoji := make(map[string]*model.ReactionCount)
+	for _, reaction := range list {
+		count, ok := byEmoji[reaction.Emoji]
+		if !ok {
+			count = &model.ReactionCount{Emoji: reaction.Emoji}
+			byEmoji[reaction.Emoji] = count
+			counts = append(counts, count)
+		}
+		count.Count++
+		if userID != "" && reaction.UserID == userID {
+			count.ViewerReacted = true
+		}
+	}
+	return counts
+}
+
+// CreateForum добавляет раздел в конец списка
+func (r *InMemoryRepository) CreateForum(ctx context.Context, forum *model.Forum) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, f := range r.sortedForums {
+		if f.Name == forum.Name {
+			return Conflict("раздел с таким названием уже существует")
+		}
+	}
+
+	forum.CreatedAt = now()
+	forum.Position = 1
+	if n := len(r.sortedForums); n > 0 {
+		forum.Position = r.sortedForums[n-1].Position + 1
+	}
+
+	r.forums[forum.ID] = forum
+	r.sortedForums = append(r.sortedForums, forum)
+
+	return nil
+}
+
+// GetForums возвращает разделы по Position
+func (r *InMemoryRepository) GetForums(ctx context.Context) ([]*model.Forum, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return append([]*model.Forum{}, r.sortedForums...), nil
+}
+
+// GetForumByID возвращает раздел по ID
+func (r *InMemoryRepository) GetForumByID(ctx context.Context, id string) (*model.Forum, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	forum, ok := r.forums[id]
+	if !ok {
+		return nil, NotFound("раздел не найден")
+	}
+
+	return forum, nil
+}
+
+// ReorderForums расставляет разделы в порядке ids
+func (r *InMemoryRepository) ReorderForums(ctx context.Context, ids []string) ([]*model.Forum, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if err := checkForumOrder(ids, len(r.forums), func(id string) bool {
+		_, ok := r.forums[id]
+		return ok
+	}); err != nil {
+		return nil, err
+	}
+
+	// Разделы заменяются копиями: выданные читателям не меняются
+	sorted := make([]*model.Forum, len(ids))
+	for i, id := range ids {
+		forum := *r.forums[id]
+		forum.Position = i + 1
+		r.forums[id] = &forum
+		sorted[i] = &forum
+	}
+	r.sortedForums = sorted
+
+	return append([]*model.Forum{}, sorted...), nil
+}
+
+// GetTags считает теги неудалённых постов
+func (r *InMemoryRepository) GetTags(ctx context.Context, forumID string, first int) ([]*model.Tag, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	counts := make(map[string]int)
+	for _, post := range r.filteredPosts(model.PostFilter{ForumID: forumID}) {
+		for _, tag := range post.Tags {
+			counts[tag]++
+		}
+	}
+
+	tags := make([]*model.Tag, 0, len(counts))
+	for name, count := range counts {
+		tags = append(tags, &model.Tag{Name: name, PostCount: count})
+	}
+	sort.Slice(tags, func(i, j int) bool {
+		if tags[i].PostCount != tags[j].PostCount {
+			return tags[i].PostCount > tags[j].PostCount
+		}
+		return tags[i].Name < tags[j].Name
+	})
+
+	if len(tags) > first {
+		tags = tags[:first]
+	}
+
+	return tags, nil
+}
+
+// AddBookmark сохраняет запись в закладки пользователя
+func (r *InMemoryRepository) AddBookmark(ctx context.Context, userID, targetID string) (*model.Bookmark, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	postID, err := r.activeTarget(targetID)
+	if err != nil {
+		return nil, err
+	}
+	// Закладки на комментарии удалённого поста удаляются вместе с ним
+	if r.posts[postID].DeletedAt != nil {
+		return nil, ErrPostDeleted
+	}
+
+	if i := indexOfBookmark(r.bookmarks[userID], targetID); i >= 0 {
+		return r.bookmarkWithTarget(r.bookmarks[userID][i]), nil
+	}
+
+	// Список упорядочен по убыванию (created_at, target_id), как его читает
+	// keysetPage: при равном времени место определяет target_id
+	bookmark := &model.Bookmark{UserID: userID, TargetID: targetID, CreatedAt: now()}
+	list := r.bookmarks[userID]
+	i := sort.Search(len(list), func(i int) bool {
+		return model.OrderNewest.Before(bookmark.Cursor(), list[i].Cursor())
+	})
+	r.bookmarks[userID] = insertAt(list, i, bookmark)
+	if r.bookmarkedBy[targetID] == nil {
+		r.bookmarkedBy[targetID] = make(map[string]bool)
+	}
+	r.bookmarkedBy[targetID][userID] = true
+
+	return r.bookmarkWithTarget(bookmark), nil
+}
+
+// RemoveBookmark убирает запись из закладок пользователя
+func (r *InMemoryRepository) RemoveBookmark(ctx context.Context, userID, targetID string) (*model.Bookmark, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	bookmark := &model.Bookmark{UserID: userID, TargetID: targetID}
+	if list := r.bookmarks[userID]; r.bookmarkedBy[targetID][userID] {
+		i := indexOfBookmark(list, targetID)
+		bookmark = list[i]
+		r.bookmarks[userID] = append(list[:i:i], list[i+1:]...)
+		delete(r.bookmarkedBy[targetID], userID)
+		if len(r.bookmarkedBy[targetID]) == 0 {
+			delete(r.bookmarkedBy, targetID)
+		}
+	}
+
+	result := r.bookmarkWithTarget(bookmark)
+	if result.Post == nil && result.Comment == nil {
+		return nil, NotFound("пост или комментарий не найден")
+	}
+
+	return result, nil
+}
+
+// GetBookmarks возвращает страницу закладок пользователя
+func (r *InMemoryRepository) GetBookmarks(ctx context.Context, userID string, first int, after *model.Cursor) (*model.Page[*model.Bookmark], error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	page := keysetPage(r.bookmarks[userID], model.OrderNewest, model.OrderNewest, (*model.Bookmark).Cursor, first, after)
+
+	// Закладки в хранилище не меняются: в страницу попадают копии с записями
+	items := make([]*model.Bookmark, len(page.Items))
+	for i, bookmark := range page.Items {
+		items[i] = r.bookmarkWithTarget(bookmark)
+	}
+	page.Items = items
+
+	return page, nil
+}
+
+// GetBookmarked сообщает, какие записи в закладках пользователя
+func (r *InMemoryRepository) GetBookmarked(ctx context.Context, userID string, targetIDs []string) (map[string]bool, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	bookmarked := make(map[string]bool, len(targetIDs))
+	for _, id := range targetIDs {
+		if r.bookmarkedBy[id][userID] {
+			bookmarked[id] = true
+		}
+	}
+
+	return bookmarked, nil
+}
+
+// bookmarkWithTarget возвращает копию закладки с сохранённой записью.
+// Вызывается под блокировкой
+func (r *InMemoryRepository) bookmarkWithTarget(bookmark *model.Bookmark) *model.Bookmark {
+	result := *bookmark
+	if post, ok := r.posts[bookmark.TargetID]; ok {
+		result.Post = post
+	} else {
+		result.Comment = r.commentsByID[bookmark.TargetID]
+	}
+	return &result
+}
+
+// removeBookmarks удаляет закладки на удалённую запись.
+// Вызывается под блокировкой на запись
+func (r *InMemoryRepository) removeBookmarks(targetID string) {
+	for userID := range r.bookmarkedBy[targetID] {
+		list := r.bookmarks[userID]
+		i := indexOfBookmark(list, targetID)
+		r.bookmarks[userID] = append(list[:i:i], list[i+1:]...)
+	}
+	delete(r.bookmarkedBy, targetID)
+}
+
+func indexOfBookmark(list []*model.Bookmark, targetID string) int {
+	for i, bookmark := range list {
+		if bookmark.TargetID == targetID {
+			return i
+		}
+	}
+	return -1
+}
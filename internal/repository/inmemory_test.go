@@ -0,0 +1,516 @@
+package repository
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/22Fariz22/forum/internal/model"
+	"github.com/google/uuid"
+)
+
+func newTestRepo(t *testing.T) (*InMemoryRepository, *model.User) {
+	t.Helper()
+
+	repo := NewInMemoryRepository().(*InMemoryRepository)
+	user := &model.User{ID: uuid.New().String(), Username: "tester"}
+	if err := repo.CreateUser(context.Background(), user); err != nil {
+		t.Fatal(err)
+	}
+	return repo, user
+}
+
+func createTestPost(t *testing.T, repo Repository, user *model.User) *model.Post {
+	t.Helper()
+
+	post := &model.Post{
+		ID:            uuid.New().String(),
+		Title:         "title",
+		Content:       "content",
+		AllowComments: true,
+		AuthorID:      user.ID,
+	}
+	if err := repo.CreatePost(context.Background(), post); err != nil {
+		t.Fatal(err)
+	}
+	return post
+}
+
+func createTestComment(t *testing.T, repo Repository, user *model.User, postID string, parentID *string) *model.Comment {
+	t.Helper()
+
+	comment, err := addComment(repo, user, postID, parentID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return comment
+}
+
+// addComment добавляет комментарий или ответ. Без t.Fatal: вызывается из горутин
+func addComment(repo Repository, user *model.User, postID string, parentID *string) (*model.Comment, error) {
+	comment := &model.Comment{
+		ID:       uuid.New().String(),
+		PostID:   postID,
+		ParentID: parentID,
+		Content:  "comment",
+		AuthorID: user.ID,
+		Username: user.Username,
+	}
+	if parentID == nil {
+		return repo.CreateCommentOnPost(context.Background(), comment)
+	}
+	return repo.ReplyToComment(context.Background(), comment)
+}
+
+// readAll читает поля всех постов и комментариев, как это делают резолверы
+// после снятия блокировки
+func readAll(repo Repository, postID string) int {
+	ctx := context.Background()
+	n := 0
+
+	posts, _ := repo.GetPostsPage(ctx, model.OrderNewest, model.PostFilter{}, 100, nil)
+	for _, p := range posts.Items {
+		n += p.CommentCount + p.Score + len(p.Title) + len(p.Content)
+		if p.AllowComments || p.EditedAt != nil || p.DeletedAt != nil {
+			n++
+		}
+	}
+	if post, err := repo.GetPostByID(ctx, postID); err == nil {
+		n += post.CommentCount + post.Upvotes + post.Downvotes
+	}
+
+	comments, _ := repo.GetThreadPage(ctx, postID, 100, "")
+	for _, c := range comments.Items {
+		n += c.ReplyCount + c.Score + c.Upvotes + c.Downvotes + len(c.Content)
+		if c.HaveComments || c.EditedAt != nil || c.DeletedAt != nil {
+			n++
+		}
+	}
+	return n
+}
+
+// runConcurrently выполняет write в нескольких горутинах, пока другие
+// горутины читают пост. Гонки ловит go test -race
+func runConcurrently(t *testing.T, repo Repository, postID string, write func(i int) error) {
+	t.Helper()
+
+	stop := make(chan struct{})
+	var readers sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		readers.Add(1)
+		go func() {
+			defer readers.Done()
+			for {
+				select {
+				case <-stop:
+					return
+				default:
+					readAll(repo, postID)
+				}
+			}
+		}()
+	}
+
+	var writers sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		writers.Add(1)
+		go func(i int) {
+			defer writers.Done()
+			for j := 0; j < 20; j++ {
+				if err := write(i*20 + j); err != nil {
+					t.Error(err)
+				}
+			}
+		}(i)
+	}
+	writers.Wait()
+	close(stop)
+	readers.Wait()
+}
+
+func TestInMemoryCommentCountersCopyOnWrite(t *testing.T) {
+	repo, user := newTestRepo(t)
+	ctx := context.Background()
+	post := createTestPost(t, repo, user)
+	root := createTestComment(t, repo, user, post.ID, nil)
+
+	runConcurrently(t, repo, post.ID, func(i int) error {
+		reply, err := addComment(repo, user, post.ID, &root.ID)
+		if err != nil || i%2 == 1 {
+			return err
+		}
+		_, err = repo.DeleteComment(ctx, reply.ID, user.ID)
+		return err
+	})
+
+	// Выданные раньше значения не меняются
+	if post.CommentCount != 0 || root.ReplyCount != 0 {
+		t.Fatalf("выданные значения изменились: CommentCount=%d, ReplyCount=%d", post.CommentCount, root.ReplyCount)
+	}
+
+	got, err := repo.GetPostByID(ctx, post.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.CommentCount != 41 {
+		t.Errorf("CommentCount = %d, ожидалось 41", got.CommentCount)
+	}
+
+	gotRoot, err := repo.GetCommentByID(ctx, root.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if gotRoot.ReplyCount != 40 || !gotRoot.HaveComments {
+		t.Errorf("ReplyCount = %d, HaveComments = %v, ожидалось 40, true", gotRoot.ReplyCount, gotRoot.HaveComments)
+	}
+
+	// Списки ссылаются на актуальные копии
+	page, err := repo.GetCommentsPage(ctx, post.ID, model.OrderOldest, 10, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(page.Items) != 1 || page.Items[0] != gotRoot {
+		t.Errorf("список комментариев поста не содержит актуальную копию")
+	}
+	posts, err := repo.GetPostsPage(ctx, model.OrderNewest, model.PostFilter{}, 10, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(posts.Items) != 1 || posts.Items[0] != got {
+		t.Errorf("лента не содержит актуальную копию поста")
+	}
+}
+
+func TestInMemoryVoteCopyOnWrite(t *testing.T) {
+	repo, user := newTestRepo(t)
+	ctx := context.Background()
+	post := createTestPost(t, repo, user)
+	comment := createTestComment(t, repo, user, post.ID, nil)
+
+	voters := make([]*model.User, 80)
+	for i := range voters {
+		voters[i] = &model.User{ID: uuid.New().String(), Username: "voter"}
+	}
+
+	runConcurrently(t, repo, post.ID, func(i int) error {
+		value := 1
+		if i%4 == 0 {
+			value = -1
+		}
+		if _, err := repo.Vote(ctx, voters[i].ID, post.ID, value); err != nil {
+			return err
+		}
+		_, err := repo.Vote(ctx, voters[i].ID, comment.ID, value)
+		return err
+	})
+
+	if post.Score != 0 || comment.Score != 0 {
+		t.Fatalf("выданные значения изменились: %d, %d", post.Score, comment.Score)
+	}
+
+	got, err := repo.GetPostByID(ctx, post.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Upvotes != 60 || got.Downvotes != 20 || got.Score != 40 {
+		t.Errorf("пост: %d/%d/%d, ожидалось 60/20/40", got.Upvotes, got.Downvotes, got.Score)
+	}
+
+	gotComment, err := repo.GetCommentByID(ctx, comment.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if gotComment.Upvotes != 60 || gotComment.Downvotes != 20 || gotComment.Score != 40 {
+		t.Errorf("комментарий: %d/%d/%d, ожидалось 60/20/40", gotComment.Upvotes, gotComment.Downvotes, gotComment.Score)
+	}
+
+	// Отмена голоса – тоже копия
+	votable, err := repo.Vote(ctx, voters[0].ID, post.ID, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if votable.Post == got || votable.Post.Score != 41 || got.Score != 40 {
+		t.Errorf("отмена голоса изменила выданный пост")
+	}
+}
+
+func TestInMemoryEditCopyOnWrite(t *testing.T) {
+	repo, user := newTestRepo(t)
+	ctx := context.Background()
+	post := createTestPost(t, repo, user)
+	root := createTestComment(t, repo, user, post.ID, nil)
+	createTestComment(t, repo, user, post.ID, &root.ID)
+
+	runConcurrently(t, repo, post.ID, func(i int) error {
+		title := "title"
+		if _, err := repo.UpdatePost(ctx, post.ID, user.ID, &title, nil); err != nil {
+			return err
+		}
+		_, err := repo.UpdateComment(ctx, root.ID, user.ID, "edited")
+		return err
+	})
+
+	if post.EditedAt != nil || root.EditedAt != nil || root.Content != "comment" {
+		t.Fatal("правка изменила выданные значения")
+	}
+
+	deletedPost, err := repo.DeletePost(ctx, post.ID, user.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	deletedRoot, err := repo.DeleteComment(ctx, root.ID, user.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if post.DeletedAt != nil || post.Title != "title" || root.DeletedAt != nil {
+		t.Fatal("удаление изменило выданные значения")
+	}
+	if deletedPost.DeletedAt == nil || deletedPost.Content != model.DeletedContent {
+		t.Errorf("пост не удалён: %+v", deletedPost)
+	}
+
+	// Заглушка комментария с ответами остаётся в ветке
+	thread, err := repo.GetThreadPage(ctx, post.ID, 10, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(thread.Items) != 2 || thread.Items[0] != deletedRoot || deletedRoot.Content != model.DeletedContent {
+		t.Errorf("ветка не содержит заглушку удалённого комментария")
+	}
+}
+
+func TestInMemorySetCommentsEnabledCopyOnWrite(t *testing.T) {
+	repo, user := newTestRepo(t)
+	ctx := context.Background()
+	post := createTestPost(t, repo, user)
+
+	runConcurrently(t, repo, post.ID, func(i int) error {
+		_, err := repo.SetCommentsEnabled(ctx, post.ID, i%2 == 0)
+		return err
+	})
+
+	if !post.AllowComments {
+		t.Fatal("выданный пост изменился")
+	}
+
+	updated, err := repo.SetCommentsEnabled(ctx, post.ID, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := repo.GetPostByID(ctx, post.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != updated || got.AllowComments {
+		t.Errorf("комментарии не отключены")
+	}
+	if _, err := addComment(repo, user, post.ID, nil); err != ErrCommentsDisabled {
+		t.Errorf("комментарий к закрытому посту: %v, ожидалось ErrCommentsDisabled", err)
+	}
+}
+
+func TestInMemoryReorderForumsCopyOnWrite(t *testing.T) {
+	repo, _ := newTestRepo(t)
+	ctx := context.Background()
+
+	var forums []*model.Forum
+	for _, name := range []string{"a", "b", "c"} {
+		forum := &model.Forum{ID: uuid.New().String(), Name: name}
+		if err := repo.CreateForum(ctx, forum); err != nil {
+			t.Fatal(err)
+		}
+		forums = append(forums, forum)
+	}
+
+	reordered, err := repo.ReorderForums(ctx, []string{forums[2].ID, forums[0].ID, forums[1].ID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if forums[2].Position != 3 {
+		t.Fatalf("выданный раздел изменился: Position = %d", forums[2].Position)
+	}
+	if reordered[0].ID != forums[2].ID || reordered[0].Position != 1 {
+		t.Errorf("первый раздел: %s/%d", reordered[0].Name, reordered[0].Position)
+	}
+
+	got, err := repo.GetForumByID(ctx, forums[2].ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Position != 1 {
+		t.Errorf("Position = %d, ожидалось 1", got.Position)
+	}
+}
+
+func TestInMemoryAddBookmarkKeepsOrder(t *testing.T) {
+	repo, user := newTestRepo(t)
+	ctx := context.Background()
+
+	posts := make([]*model.Post, 4)
+	for i := range posts {
+		posts[i] = createTestPost(t, repo, user)
+	}
+
+	// Закладки с тем же временем и из будущего: новая закладка должна встать
+	// по ключу (created_at, target_id), а не в начало списка
+	later := now().Add(time.Hour)
+	for _, post := range posts[:3] {
+		if _, err := repo.AddBookmark(ctx, user.ID, post.ID); err != nil {
+			t.Fatal(err)
+		}
+	}
+	repo.mu.Lock()
+	for _, b := range repo.bookmarks[user.ID][:2] {
+		b.CreatedAt = later
+	}
+	sort.Slice(repo.bookmarks[user.ID], func(i, j int) bool {
+		return model.OrderNewest.Before(repo.bookmarks[user.ID][i].Cursor(), repo.bookmarks[user.ID][j].Cursor())
+	})
+	repo.mu.Unlock()
+
+	if _, err := repo.AddBookmark(ctx, user.ID, posts[3].ID); err != nil {
+		t.Fatal(err)
+	}
+
+	full, err := repo.GetBookmarks(ctx, user.ID, 10, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 1; i < len(full.Items); i++ {
+		if !model.OrderNewest.Before(full.Items[i-1].Cursor(), full.Items[i].Cursor()) {
+			t.Fatalf("закладки %d и %d не по убыванию (created_at, target_id)", i-1, i)
+		}
+	}
+
+	// Постраничный обход выдаёт каждую закладку ровно один раз
+	var after *model.Cursor
+	var seen []string
+	for {
+		page, err := repo.GetBookmarks(ctx, user.ID, 1, after)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, b := range page.Items {
+			seen = append(seen, b.TargetID)
+			c := b.Cursor()
+			after = &c
+		}
+		if !page.HasNextPage {
+			break
+		}
+	}
+	if len(seen) != len(full.Items) {
+		t.Fatalf("обход выдал %d закладок, ожидалось %d", len(seen), len(full.Items))
+	}
+	for i, b := range full.Items {
+		if seen[i] != b.TargetID {
+			t.Fatalf("обход: позиция %d – %s, ожидалось %s", i, seen[i], b.TargetID)
+		}
+	}
+}
+
+func TestInMemoryCreateUserSetsCreatedAt(t *testing.T) {
+	repo, user := newTestRepo(t)
+
+	got, err := repo.GetUserByID(context.Background(), user.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.CreatedAt.IsZero() {
+		t.Error("CreatedAt не заполнено")
+	}
+}
+
+func TestInMemoryDeletePostRemovesCommentBookmarks(t *testing.T) {
+	repo, user := newTestRepo(t)
+	ctx := context.Background()
+	post := createTestPost(t, repo, user)
+	other := createTestPost(t, repo, user)
+	root := createTestComment(t, repo, user, post.ID, nil)
+	reply := createTestComment(t, repo, user, post.ID, &root.ID)
+	kept := createTestComment(t, repo, user, other.ID, nil)
+
+	for _, id := range []string{post.ID, root.ID, reply.ID, kept.ID} {
+		if _, err := repo.AddBookmark(ctx, user.ID, id); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if _, err := repo.DeletePost(ctx, post.ID, user.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	page, err := repo.GetBookmarks(ctx, user.ID, 10, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if page.TotalCount != 1 || len(page.Items) != 1 || page.Items[0].TargetID != kept.ID {
+		t.Fatalf("остались закладки %d, ожидалась только закладка на комментарий другого поста", len(page.Items))
+	}
+
+	bookmarked, err := repo.GetBookmarked(ctx, user.ID, []string{post.ID, root.ID, reply.ID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(bookmarked) != 0 {
+		t.Errorf("GetBookmarked: %v, ожидалось пусто", bookmarked)
+	}
+
+	// Комментарий удалённого поста снова сохранить нельзя
+	if _, err := repo.AddBookmark(ctx, user.ID, root.ID); err != ErrPostDeleted {
+		t.Errorf("закладка на комментарий удалённого поста: %v, ожидалось ErrPostDeleted", err)
+	}
+}
+
+func TestInMemoryGetCommentsEditedAfter(t *testing.T) {
+	repo, user := newTestRepo(t)
+	ctx := context.Background()
+	post := createTestPost(t, repo, user)
+	first := createTestComment(t, repo, user, post.ID, nil)
+	second := createTestComment(t, repo, user, post.ID, nil)
+	createTestComment(t, repo, user, post.ID, nil)
+
+	edit := func(id string) *model.Comment {
+		t.Helper()
+		time.Sleep(time.Millisecond)
+		comment, err := repo.UpdateComment(ctx, id, user.ID, "edited")
+		if err != nil {
+			t.Fatal(err)
+		}
+		return comment
+	}
+
+	edited := edit(second.ID)
+	edit(first.ID)
+	// Повторная правка переносит комментарий в конец
+	edit(second.ID)
+
+	all, err := repo.GetCommentsEditedAfter(ctx, post.ID, model.Cursor{}, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(all) != 2 || all[0].ID != first.ID || all[1].ID != second.ID {
+		t.Fatalf("получено %d правок, ожидалось first, second", len(all))
+	}
+
+	// После курсора правки – только комментарии, изменённые позже
+	if got, _ := repo.GetCommentsEditedAfter(ctx, post.ID, model.EditCursorOf(edited), 10); len(got) != 2 {
+		t.Errorf("после первой правки: %d, ожидалось 2", len(got))
+	}
+	if got, _ := repo.GetCommentsEditedAfter(ctx, post.ID, model.EditCursorOf(all[0]), 10); len(got) != 1 || got[0].ID != second.ID {
+		t.Errorf("после правки first: %d, ожидалось только second", len(got))
+	}
+	if got, _ := repo.GetCommentsEditedAfter(ctx, post.ID, model.EditCursorOf(all[1]), 10); len(got) != 0 {
+		t.Errorf("после последней правки: %d, ожидалось 0", len(got))
+	}
+
+	// Удалённые комментарии досылает commentDeleted
+	if _, err := repo.DeleteComment(ctx, first.ID, user.ID); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := repo.GetCommentsEditedAfter(ctx, post.ID, model.Cursor{}, 10); len(got) != 1 || got[0].ID != second.ID {
+		t.Errorf("после удаления: %d, ожидалось только second", len(got))
+	}
+}
@@ -0,0 +1,273 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/22Fariz22/forum/config"
+	"github.com/22Fariz22/forum/internal/model"
+	"github.com/22Fariz22/forum/pkg/db/postgres"
+	"github.com/22Fariz22/forum/pkg/logger"
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+)
+
+// postgresDSNEnv – строка подключения к пустой тестовой базе. Без неё
+// проверки Postgres пропускаются. Таблицы базы очищаются перед тестом
+const postgresDSNEnv = "FORUM_TEST_POSTGRES_DSN"
+
+var allOrders = []model.SortOrder{
+	model.OrderNewest,
+	model.OrderOldest,
+	model.OrderTop,
+	model.OrderHot,
+	model.OrderMostReplied,
+}
+
+// Голоса и ответы набора: у многих записей равные рейтинг и активность,
+// чтобы порядок решали created_at и вес HOT на границах страниц
+var (
+	postVotes    = []int{3, 1, 1, 0, 2, 0, 1, 3, -1, 2, 1, 0}
+	postComments = []int{0, 2, 1, 1, 0, 3, 1, 0, 2, 0, 1, 2}
+	commentVotes = []int{1, 0, 2, 1, -1, 0, 2, 1, 0}
+	replyCounts  = []int{2, 1, 0, 1, 3, 0, 0, 2, 1}
+)
+
+// fixtureID – ID записи набора, одинаковый в обоих хранилищах
+func fixtureID(name string, i int) string {
+	return uuid.NewSHA1(uuid.NameSpaceOID, []byte(fmt.Sprintf("%s-%d", name, i))).String()
+}
+
+// seedOrderFixture создаёт одинаковый набор постов, комментариев и голосов и
+// возвращает ID поста с обсуждением. Записи создаются по очереди с паузой,
+// поэтому created_at различаются и порядок одинаков в обоих хранилищах
+func seedOrderFixture(t *testing.T, repo Repository) string {
+	t.Helper()
+	ctx := context.Background()
+
+	author := &model.User{ID: fixtureID("author", 0), Username: "author"}
+	if err := repo.CreateUser(ctx, author); err != nil {
+		t.Fatal(err)
+	}
+	voters := make([]*model.User, 4)
+	for i := range voters {
+		voters[i] = &model.User{ID: fixtureID("voter", i), Username: fmt.Sprintf("voter%d", i)}
+		if err := repo.CreateUser(ctx, voters[i]); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	vote := func(targetID string, score int) {
+		t.Helper()
+		value := 1
+		if score < 0 {
+			value, score = -1, -score
+		}
+		for i := 0; i < score; i++ {
+			if _, err := repo.Vote(ctx, voters[i].ID, targetID, value); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	comment := func(id, postID string, parentID *string) {
+		t.Helper()
+		c := &model.Comment{
+			ID:       id,
+			PostID:   postID,
+			ParentID: parentID,
+			Content:  "comment",
+			AuthorID: author.ID,
+			Username: author.Username,
+		}
+		var err error
+		if parentID == nil {
+			_, err = repo.CreateCommentOnPost(ctx, c)
+		} else {
+			_, err = repo.ReplyToComment(ctx, c)
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	for i := range postVotes {
+		post := &model.Post{
+			ID:            fixtureID("post", i),
+			Title:         "title",
+			Content:       "content",
+			AllowComments: true,
+			AuthorID:      author.ID,
+		}
+		if err := repo.CreatePost(ctx, post); err != nil {
+			t.Fatal(err)
+		}
+		time.Sleep(time.Millisecond)
+		vote(post.ID, postVotes[i])
+	}
+
+	for i, n := range postComments {
+		for j := 0; j < n; j++ {
+			comment(fixtureID(fmt.Sprintf("post-%d-comment", i), j), fixtureID("post", i), nil)
+		}
+	}
+
+	// Обсуждение под отдельным постом: комментарии верхнего уровня с ответами и голосами
+	discussion := &model.Post{
+		ID:            fixtureID("discussion", 0),
+		Title:         "discussion",
+		Content:       "content",
+		AllowComments: true,
+		AuthorID:      author.ID,
+	}
+	if err := repo.CreatePost(ctx, discussion); err != nil {
+		t.Fatal(err)
+	}
+	for i := range commentVotes {
+		comment(fixtureID("comment", i), discussion.ID, nil)
+	}
+	for i, n := range replyCounts {
+		parentID := fixtureID("comment", i)
+		for j := 0; j < n; j++ {
+			comment(fixtureID(fmt.Sprintf("comment-%d-reply", i), j), discussion.ID, &parentID)
+		}
+		vote(parentID, commentVotes[i])
+	}
+
+	return discussion.ID
+}
+
+// walkPages обходит список страницами по first записей и возвращает ID
+// в порядке выдачи. Курсоры строятся так же, как в резолверах
+func walkPages[T any](t *testing.T, first int, fetch func(after *model.Cursor) (*model.Page[T], error), cursorOf func(T) model.Cursor, idOf func(T) string) []string {
+	t.Helper()
+
+	var ids []string
+	var after *model.Cursor
+	for pages := 0; ; pages++ {
+		if pages > 100 {
+			t.Fatal("обход страниц не завершился")
+		}
+		page, err := fetch(after)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, item := range page.Items {
+			ids = append(ids, idOf(item))
+			c := cursorOf(item)
+			after = &c
+		}
+		if !page.HasNextPage {
+			return ids
+		}
+	}
+}
+
+// orderedIDs возвращает для каждого порядка ID постов ленты и комментариев
+// обсуждения, собранные по страницам, и проверяет, что страницы совпадают
+// с выдачей одним запросом
+func orderedIDs(t *testing.T, repo Repository, discussionID string) map[string][]string {
+	t.Helper()
+	ctx := context.Background()
+
+	result := make(map[string][]string)
+	for _, order := range allOrders {
+		order := order
+
+		fetchPosts := func(first int) func(*model.Cursor) (*model.Page[*model.Post], error) {
+			return func(after *model.Cursor) (*model.Page[*model.Post], error) {
+				return repo.GetPostsPage(ctx, order, model.PostFilter{}, first, after)
+			}
+		}
+		postID := func(p *model.Post) string { return p.ID }
+		all := walkPages(t, 100, fetchPosts(100), order.PostCursor, postID)
+		for _, first := range []int{1, 3, 5} {
+			assertSameIDs(t, fmt.Sprintf("посты %s по %d", order, first), walkPages(t, first, fetchPosts(first), order.PostCursor, postID), all)
+		}
+		result["posts/"+string(order)] = all
+
+		fetchComments := func(first int) func(*model.Cursor) (*model.Page[*model.Comment], error) {
+			return func(after *model.Cursor) (*model.Page[*model.Comment], error) {
+				return repo.GetCommentsPage(ctx, discussionID, order, first, after)
+			}
+		}
+		commentID := func(c *model.Comment) string { return c.ID }
+		all = walkPages(t, 100, fetchComments(100), order.CommentCursor, commentID)
+		for _, first := range []int{1, 2, 4} {
+			assertSameIDs(t, fmt.Sprintf("комментарии %s по %d", order, first), walkPages(t, first, fetchComments(first), order.CommentCursor, commentID), all)
+		}
+		result["comments/"+string(order)] = all
+	}
+
+	return result
+}
+
+func assertSameIDs(t *testing.T, name string, got, want []string) {
+	t.Helper()
+
+	if len(got) != len(want) {
+		t.Errorf("%s: %d записей, ожидалось %d", name, len(got), len(want))
+		return
+	}
+	for i := range got {
+		if got[i] != want[i] {
+			t.Errorf("%s: позиция %d – %s, ожидалось %s", name, i, got[i], want[i])
+			return
+		}
+	}
+}
+
+func newPostgresTestRepo(t *testing.T) Repository {
+	t.Helper()
+
+	dsn := os.Getenv(postgresDSNEnv)
+	if dsn == "" {
+		t.Skipf("%s не задан", postgresDSNEnv)
+	}
+
+	log := logger.NewApiLogger(&config.Config{Logger: config.Logger{Level: "error"}})
+	log.InitLogger()
+	if err := postgres.Migrate(log, dsn); err != nil {
+		t.Fatal(err)
+	}
+
+	db, err := sqlx.Connect("pgx", dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	if _, err := db.Exec(`TRUNCATE users, posts, comments, revisions, votes, reactions, forums, bookmarks`); err != nil {
+		t.Fatal(err)
+	}
+
+	repo, err := NewPostgresRepository(db, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return repo
+}
+
+// TestOrdersAcrossBackends прогоняет один набор через оба хранилища: во
+// всех порядках обход страницами совпадает с выдачей одним запросом, а
+// хранилища выдают записи в одном порядке
+func TestOrdersAcrossBackends(t *testing.T) {
+	memory := NewInMemoryRepository()
+	want := orderedIDs(t, memory, seedOrderFixture(t, memory))
+
+	if len(want["posts/"+string(model.OrderNewest)]) != len(postVotes)+1 {
+		t.Fatalf("в ленте %d постов", len(want["posts/"+string(model.OrderNewest)]))
+	}
+
+	t.Run("Postgres", func(t *testing.T) {
+		repo := newPostgresTestRepo(t)
+		got := orderedIDs(t, repo, seedOrderFixture(t, repo))
+
+		for key, ids := range want {
+			assertSameIDs(t, key, got[key], ids)
+		}
+	})
+}
@@ -5,79 +5,173 @@ import (
 	"database/sql"
 	"errors"
 	"fmt"
-	// "time"
+	"strings"
+	"time"
 
 	"github.com/22Fariz22/forum/internal/model"
+	"github.com/22Fariz22/forum/pkg/search"
+	"github.com/google/uuid"
 	"github.com/jmoiron/sqlx"
 	"github.com/lib/pq"
 )
 
 type PostgresRepository struct {
-	db *sqlx.DB
+	db      *sqlx.DB
+	timeout time.Duration // дедлайн одной операции с базой, 0 – без ограничения
 }
 
-func NewPostgresRepository(db *sqlx.DB) (Repository, error) {
+func NewPostgresRepository(db *sqlx.DB, timeout time.Duration) (Repository, error) {
 	if err := db.Ping(); err != nil {
 		return nil, err
 	}
 
-	return &PostgresRepository{db: db}, nil
+	return &PostgresRepository{db: db, timeout: timeout}, nil
+}
+
+// withTimeout ограничивает время одной операции с базой. Отмена запроса
+// клиентом прерывает и SQL-запрос, так как контекст наследуется.
+func (r *PostgresRepository) withTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	if r.timeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, r.timeout)
+}
+
+// postColumns – столбцы posts в порядке scanPost
+const postColumns = `id, title, content, allow_comments, author_id, have_comments, created_at, comment_count, edited_at, deleted_at, score, upvotes, downvotes, forum_id, tags`
+
+// commentColumns – столбцы comments в порядке scanComment
+const commentColumns = `id, post_id, parent_id, content, author_id, username, have_comments, created_at, depth, reply_count, edited_at, deleted_at, score, upvotes, downvotes`
+
+// rowScanner – *sql.Row или *sql.Rows
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// scanPost читает пост, выбранный столбцами postColumns
+func scanPost(row rowScanner) (*model.Post, error) {
+	post := &model.Post{}
+	err := row.Scan(
+		&post.ID,
+		&post.Title,
+		&post.Content,
+		&post.AllowComments,
+		&post.AuthorID,
+		&post.HaveComments,
+		&post.CreatedAt,
+		&post.CommentCount,
+		&post.EditedAt,
+		&post.DeletedAt,
+		&post.Score,
+		&post.Upvotes,
+		&post.Downvotes,
+		&post.ForumID,
+		pq.Array(&post.Tags),
+	)
+	if err != nil {
+		return nil, err
+	}
+	return post, nil
+}
+
+// scanComment читает комментарий, выбранный столбцами commentColumns, и
+// заполняет автора. extra – столбцы, выбранные после commentColumns
+func scanComment(row rowScanner, extra ...interface{}) (*model.Comment, error) {
+	comment := &model.Comment{}
+	dest := []interface{}{
+		&comment.ID,
+		&comment.PostID,
+		&comment.ParentID,
+		&comment.Content,
+		&comment.AuthorID,
+		&comment.Username,
+		&comment.HaveComments,
+		&comment.CreatedAt,
+		&comment.Depth,
+		&comment.ReplyCount,
+		&comment.EditedAt,
+		&comment.DeletedAt,
+		&comment.Score,
+		&comment.Upvotes,
+		&comment.Downvotes,
+	}
+	if err := row.Scan(append(dest, extra...)...); err != nil {
+		return nil, err
+	}
+
+	comment.Author = &model.User{
+		ID:       comment.AuthorID,
+		Username: comment.Username,
+	}
+	return comment, nil
 }
 
 // CreateUser создаем пользователя
-func (r *PostgresRepository) CreateUser(user *model.User) error {
+func (r *PostgresRepository) CreateUser(ctx context.Context, user *model.User) error {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
 	// Проверяем, что username не пустой (это уже должно быть проверено в резолвере)
 	if user.Username == "" {
-		return fmt.Errorf("username cannot be empty")
+		return Validation("username не может быть пустым")
 	}
 
 	// SQL-запрос для вставки пользователя
 	query := `
 		INSERT INTO users (id, username, created_at)
-		VALUES ($1, $2, NOW())
+		VALUES ($1, $2, $3)
 	`
 
+	user.CreatedAt = now()
+
 	// Выполняем запрос
-	_, err := r.db.Exec(query, user.ID, user.Username)
+	_, err := r.db.ExecContext(ctx, query, user.ID, user.Username, user.CreatedAt)
 	if err != nil {
 		// Обрабатываем ошибки
-		switch {
-		case isDuplicateKeyError(err):
-			return fmt.Errorf("username already exists") // 409 Conflict
-		default:
-			return fmt.Errorf("failed to create user: %w", err) // 500 Internal Server Error
+		if isDuplicateKeyError(err) {
+			return Conflict("пользователь уже существует")
 		}
+		return wrapDBError(err, "failed to create user")
 	}
 
 	return nil
 }
 
 // GetUserByID получаем пользователя по ID
-func (r *PostgresRepository) GetUserByID(id string) (*model.User, error) {
-	query := `SELECT id, username FROM users WHERE id = $1`
+func (r *PostgresRepository) GetUserByID(ctx context.Context, id string) (*model.User, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	query := `SELECT id, username, created_at FROM users WHERE id = $1`
 
 	var user model.User
-	err := r.db.QueryRowContext(context.Background(), query, id).Scan(&user.ID, &user.Username)
+	err := r.db.QueryRowContext(ctx, query, id).Scan(&user.ID, &user.Username, &user.CreatedAt)
 	if err != nil {
-		if err == sql.ErrNoRows {
-			return nil, errors.New("пользователь не найден")
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, NotFound("пользователь не найден")
 		}
-		return nil, err
+		return nil, wrapDBError(err, "failed to fetch user")
 	}
 
 	return &user, nil
 }
 
 // CreatePost создаем пост
-func (r *PostgresRepository) CreatePost(post *model.Post) error {
+func (r *PostgresRepository) CreatePost(ctx context.Context, post *model.Post) error {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
 	// SQL-запрос для вставки поста
 	query := `
-		INSERT INTO posts (id, title, content, allow_comments, have_comments, author_id)
-		VALUES ($1, $2, $3, $4, $5, $6)
+		INSERT INTO posts (id, title, content, allow_comments, have_comments, author_id, created_at, forum_id, tags)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
 	`
 
+	post.CreatedAt = now()
+
 	// Выполняем запрос
-	_, err := r.db.Exec(
+	_, err := r.db.ExecContext(
+		ctx,
 		query,
 		post.ID,
 		post.Title,
@@ -85,96 +179,299 @@ func (r *PostgresRepository) CreatePost(post *model.Post) error {
 		post.AllowComments,
 		post.HaveComments,
 		post.AuthorID,
+		post.CreatedAt,
+		post.ForumID,
+		pq.Array(postTags(post)),
 	)
 	if err != nil {
 		// Обрабатываем ошибки
-		switch {
-		case isDuplicateKeyError(err):
-			return fmt.Errorf("post with this ID already exists") // 409 Conflict
-		default:
-			return fmt.Errorf("failed to create post: %w", err) // 500 Internal Server Error
+		if isDuplicateKeyError(err) {
+			return Conflict("пост с таким ID уже существует")
 		}
+		return wrapDBError(err, "failed to create post")
 	}
 
 	return nil
 }
 
 // GetPosts получаем все посты
-func (r *PostgresRepository) GetPosts(offset int32, limit int32) ([]*model.Post, error) {
+func (r *PostgresRepository) GetPosts(ctx context.Context, order model.SortOrder, filter model.PostFilter, offset int32, limit int32) ([]*model.Post, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	_, orderBy := sortSQL(order, "comment_count")
 	query := `
-		SELECT id, title, content, allow_comments, author_id, have_comments, created_at
+		SELECT ` + postColumns + `
 		FROM posts
-		ORDER BY created_at ASC
-		LIMIT $1 OFFSET $2
-	`
+		WHERE deleted_at IS NULL`
+	cond, args := postFilterSQL(filter, nil)
+	query += cond + fmt.Sprintf(` ORDER BY %s LIMIT $%d OFFSET $%d`, orderBy, len(args)+1, len(args)+2)
+	args = append(args, limit, offset)
 
-	rows, err := r.db.Query(query, limit, offset)
+	rows, err := r.db.QueryContext(ctx, query, args...)
 	if err != nil {
-		return nil, fmt.Errorf("failed to fetch posts: %w", err)
+		return nil, wrapDBError(err, "failed to fetch posts")
 	}
 	defer rows.Close()
 
 	var posts []*model.Post
 	for rows.Next() {
-		post := &model.Post{}
-		err := rows.Scan(
-			&post.ID,
-			&post.Title,
-			&post.Content,
-			&post.AllowComments,
-			&post.AuthorID,
-			&post.HaveComments,
-			&post.CreatedAt,
-		)
+		post, err := scanPost(rows)
 		if err != nil {
-			fmt.Printf("Error during scan: %v\n", err)
-			continue // Пропускаем ошибочную строку, но продолжаем обработку
+			return nil, fmt.Errorf("failed to scan post: %w", err)
 		}
 		posts = append(posts, post)
 	}
 
 	if err = rows.Err(); err != nil {
-		return nil, fmt.Errorf("error iterating over posts: %w", err)
+		return nil, wrapDBError(err, "error iterating over posts")
 	}
 
 	return posts, nil
 }
 
 // GetPostByID получаем конкретный пост по post_id
-func (r *PostgresRepository) GetPostByID(id string) (*model.Post, error) {
+func (r *PostgresRepository) GetPostByID(ctx context.Context, id string) (*model.Post, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
 	query := `
-		SELECT id, title, content, allow_comments, author_id, have_comments, created_at
+		SELECT ` + postColumns + `
 		FROM posts
 		WHERE id = $1
 	`
 
-	post := &model.Post{}
-	err := r.db.QueryRow(query, id).Scan(
-		&post.ID,
-		&post.Title,
-		&post.Content,
-		&post.AllowComments,
-		&post.AuthorID,
-		&post.HaveComments,
-		&post.CreatedAt,
-	)
+	post, err := scanPost(r.db.QueryRowContext(ctx, query, id))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, NotFound("пост не найден")
+		}
+		return nil, wrapDBError(err, "failed to fetch post")
+	}
+
+	return post, nil
+}
+
+// SetCommentsEnabled включает или отключает комментарии к посту
+func (r *PostgresRepository) SetCommentsEnabled(ctx context.Context, postID string, enabled bool) (*model.Post, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	query := `
+		UPDATE posts
+		SET allow_comments = $2
+		WHERE id = $1 AND deleted_at IS NULL
+		RETURNING ` + postColumns + `
+	`
+
+	post, err := scanPost(r.db.QueryRowContext(ctx, query, postID, enabled))
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
-			return nil, fmt.Errorf("post not found: %w", err) // Ошибка 404
+			return nil, NotFound("пост не найден")
 		}
-		return nil, fmt.Errorf("failed to fetch post: %w", err)
+		return nil, wrapDBError(err, "failed to update post")
 	}
 
 	return post, nil
 }
 
+// UpdatePost меняет заголовок и/или текст поста. nil-поля не меняются
+func (r *PostgresRepository) UpdatePost(ctx context.Context, postID, editorID string, title, content *string) (*model.Post, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, wrapDBError(err, "failed to begin transaction")
+	}
+	defer tx.Rollback()
+
+	if err := lockPost(ctx, tx, postID); err != nil {
+		return nil, err
+	}
+
+	editedAt := now()
+	if err := savePostRevision(ctx, tx, postID, editorID, editedAt); err != nil {
+		return nil, err
+	}
+
+	query := `
+		UPDATE posts
+		SET title = COALESCE($2, title), content = COALESCE($3, content), edited_at = $4
+		WHERE id = $1
+		RETURNING ` + postColumns + `
+	`
+
+	post, err := scanPost(tx.QueryRowContext(ctx, query, postID, title, content, editedAt))
+	if err != nil {
+		return nil, wrapDBError(err, "failed to update post")
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, wrapDBError(err, "failed to commit post")
+	}
+
+	return post, nil
+}
+
+// DeletePost мягко удаляет пост: строка остаётся, чтобы не рвать ссылки на него,
+// а заголовок и текст заменяются заглушкой
+func (r *PostgresRepository) DeletePost(ctx context.Context, postID, editorID string) (*model.Post, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, wrapDBError(err, "failed to begin transaction")
+	}
+	defer tx.Rollback()
+
+	deletedAt := now()
+
+	// Прежняя версия сохраняется только при первом удалении
+	err = lockPost(ctx, tx, postID)
+	switch {
+	case err == nil:
+		if err := savePostRevision(ctx, tx, postID, editorID, deletedAt); err != nil {
+			return nil, err
+		}
+	case !errors.Is(err, ErrPostDeleted):
+		return nil, err
+	}
+
+	// Повторное удаление не меняет deleted_at
+	query := `
+		UPDATE posts
+		SET title = $2, content = $2, allow_comments = FALSE, deleted_at = COALESCE(deleted_at, $3)
+		WHERE id = $1
+		RETURNING ` + postColumns + `
+	`
+
+	post, err := scanPost(tx.QueryRowContext(ctx, query, postID, model.DeletedContent, deletedAt))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, NotFound("пост не найден")
+		}
+		return nil, wrapDBError(err, "failed to delete post")
+	}
+
+	// Закладки на пост и его комментарии: комментарии удалённого поста закрыты
+	query = `
+		DELETE FROM bookmarks
+		WHERE target_id = $1 OR target_id IN (SELECT id FROM comments WHERE post_id = $1)
+	`
+	if _, err = tx.ExecContext(ctx, query, postID); err != nil {
+		return nil, wrapDBError(err, "failed to delete post bookmarks")
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, wrapDBError(err, "failed to commit post deletion")
+	}
+
+	return post, nil
+}
+
+// lockPost блокирует строку поста до конца транзакции и проверяет, что пост
+// не удалён. Блокировка та же, что у checkCommentsAllowed, поэтому вставка
+// и удаление комментариев одного поста идут по очереди.
+func lockPost(ctx context.Context, tx *sqlx.Tx, postID string) error {
+	var deletedAt *time.Time
+	err := tx.QueryRowContext(ctx, `SELECT deleted_at FROM posts WHERE id = $1 FOR NO KEY UPDATE`, postID).Scan(&deletedAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return NotFound("пост не найден")
+		}
+		return wrapDBError(err, "failed to lock post")
+	}
+
+	if deletedAt != nil {
+		return ErrPostDeleted
+	}
+
+	return nil
+}
+
+// checkCommentsAllowed проверяет, что пост принимает комментарии. Блокировка строки
+// не даёт отключить комментарии, пока транзакция с новым комментарием не завершится.
+// Берётся сразу FOR NO KEY UPDATE, а не FOR SHARE: счётчик поста потом обновляется
+// в той же транзакции, и повышение блокировки приводило бы к взаимоблокировкам.
+func checkCommentsAllowed(ctx context.Context, tx *sqlx.Tx, postID string) error {
+	var allowComments bool
+	err := tx.QueryRowContext(ctx, `SELECT allow_comments FROM posts WHERE id = $1 FOR NO KEY UPDATE`, postID).Scan(&allowComments)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return NotFound("пост не найден")
+		}
+		return wrapDBError(err, "failed to check post")
+	}
+
+	if !allowComments {
+		return ErrCommentsDisabled
+	}
+
+	return nil
+}
+
+// incrementCommentCounters увеличивает счётчики после вставки комментария:
+// comment_count поста и, для ответа, reply_count родителя
+func incrementCommentCounters(ctx context.Context, tx *sqlx.Tx, postID string, parentID *string) error {
+	_, err := tx.ExecContext(ctx, `
+		UPDATE posts
+		SET comment_count = comment_count + 1, have_comments = TRUE
+		WHERE id = $1
+	`, postID)
+	if err != nil {
+		return wrapDBError(err, "failed to update post counters")
+	}
+
+	if parentID == nil {
+		return nil
+	}
+
+	res, err := tx.ExecContext(ctx, `
+		UPDATE comments
+		SET reply_count = reply_count + 1, have_comments = TRUE
+		WHERE id = $1
+	`, *parentID)
+	if err != nil {
+		return wrapDBError(err, "failed to update parent comment")
+	}
+
+	// Родителя могли удалить, пока ответ ждал блокировку поста
+	if n, err := res.RowsAffected(); err != nil {
+		return wrapDBError(err, "failed to update parent comment")
+	} else if n == 0 {
+		return NotFound("родительский комментарий не найден")
+	}
+
+	return nil
+}
+
 // CreateCommentOnPost создаем верхнеуровневый коментарий к посту
 func (r *PostgresRepository) CreateCommentOnPost(ctx context.Context, comment *model.Comment) (*model.Comment, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
 	query := `
-    INSERT INTO comments (id, post_id, parent_id, content, author_id,username, have_comments) 
-    VALUES ($1, $2, $3, $4, $5, $6, $7)
+    INSERT INTO comments (id, post_id, parent_id, content, author_id,username, have_comments, created_at, path, depth) 
+    VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
 `
-	_, err := r.db.ExecContext(
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, wrapDBError(err, "failed to begin transaction")
+	}
+	defer tx.Rollback()
+
+	if err := checkCommentsAllowed(ctx, tx, comment.PostID); err != nil {
+		return nil, err
+	}
+
+	// Время создания нужно сразу: по нему строятся курсоры подписок и путь в ветке
+	comment.CreatedAt = now()
+	comment.Path = model.PathSegment(comment)
+	comment.Depth = 0
+
+	_, err = tx.ExecContext(
 		ctx,
 		query,
 		comment.ID,
@@ -183,44 +480,79 @@ func (r *PostgresRepository) CreateCommentOnPost(ctx context.Context, comment *m
 		comment.Content,
 		comment.Author.ID,
 		comment.Author.Username,
-		comment.HaveComments)
+		comment.HaveComments,
+		comment.CreatedAt,
+		comment.Path,
+		comment.Depth)
 	if err != nil {
+		if isDuplicateKeyError(err) {
+			return nil, Conflict("комментарий с таким ID уже существует")
+		}
+		return nil, wrapDBError(err, "failed to create comment")
+	}
+
+	if err := incrementCommentCounters(ctx, tx, comment.PostID, nil); err != nil {
 		return nil, err
 	}
 
+	if err := tx.Commit(); err != nil {
+		return nil, wrapDBError(err, "failed to commit comment")
+	}
+
 	return comment, nil
 }
 
 // ReplyToComment создаем вложенный коментарий, то есть ответ на коментарий
 func (r *PostgresRepository) ReplyToComment(ctx context.Context, comment *model.Comment) (*model.Comment, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	if comment.ParentID == nil {
+		return nil, Validation("не указан родительский комментарий")
+	}
+
 	// Проверяем, существует ли родительский комментарий
 	var parentComment model.Comment
 	query := `
-		SELECT id, post_id AS "post_id"
+		SELECT id, post_id AS "post_id", COALESCE(path, '') AS "path", depth
 		FROM comments
 		WHERE id = $1
 	`
 
-	err := r.db.GetContext(ctx, &parentComment, query, *comment.ParentID)
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, wrapDBError(err, "failed to begin transaction")
+	}
+	defer tx.Rollback()
+
+	err = tx.GetContext(ctx, &parentComment, query, *comment.ParentID)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
-			fmt.Println("err in db.GetContext. error.Is", err)
-			return nil, fmt.Errorf("родительский комментарий не найден") // 404 Not Found
+			return nil, NotFound("родительский комментарий не найден")
 		}
-		return nil, fmt.Errorf("failed to fetch parent comment: %w", err) // 500 Internal Server Error
+		return nil, wrapDBError(err, "failed to fetch parent comment")
 	}
 
 	// Устанавливаем post_id для нового комментария
 	comment.PostID = parentComment.PostID
 
+	if err := checkCommentsAllowed(ctx, tx, comment.PostID); err != nil {
+		return nil, err
+	}
+
 	// SQL-запрос для создания нового комментария
 	insertQuery := `
-		INSERT INTO comments (id, post_id, parent_id, content, author_id, username)
-		VALUES ($1, $2, $3, $4, $5, $6)
+		INSERT INTO comments (id, post_id, parent_id, content, author_id, username, created_at, path, depth)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
 	`
 
+	comment.CreatedAt = now()
+	// Путь ответа продолжает путь родителя
+	comment.Path = model.ChildPath(parentComment.Path, comment)
+	comment.Depth = parentComment.Depth + 1
+
 	// Выполняем запрос на вставку
-	_, err = r.db.ExecContext(ctx, insertQuery,
+	_, err = tx.ExecContext(ctx, insertQuery,
 		comment.ID,
 		comment.PostID,
 		comment.ParentID,
@@ -228,145 +560,1615 @@ func (r *PostgresRepository) ReplyToComment(ctx context.Context, comment *model.
 		comment.Content,
 		comment.Author.ID,
 		comment.Author.Username,
+		comment.CreatedAt,
+		comment.Path,
+		comment.Depth,
 	)
 	if err != nil {
-		fmt.Println("err in  r.db.ExecContext:", err)
-		return nil, fmt.Errorf("failed to create comment: %w", err) // 500 Internal Server Error
+		if isDuplicateKeyError(err) {
+			return nil, Conflict("комментарий с таким ID уже существует")
+		}
+		return nil, wrapDBError(err, "failed to create comment")
 	}
 
-	// Обновляем флаг have_comments у родительского комментария
-	updateQuery := `
-		UPDATE comments
-		SET have_comments = true
-		WHERE id = $1
-	`
-	_, err = r.db.ExecContext(ctx, updateQuery, *comment.ParentID)
-	if err != nil {
-		fmt.Println("r.db.ExecContext(ctx, updateQuery, *comment.ParentID) err:", err)
-		return nil, fmt.Errorf("failed to update parent comment: %w", err) // 500 Internal Server Error
+	// Обновляем счётчики поста и родительского комментария
+	if err := incrementCommentCounters(ctx, tx, comment.PostID, comment.ParentID); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, wrapDBError(err, "failed to commit reply")
 	}
 
 	// Возвращаем созданный комментарий
 	return comment, nil
 }
 
-// GetCommentsByPostID получаем верхнеуровневые коментарии к посту используя пагинацию
-func (r *PostgresRepository) GetCommentsByPostID(postID string, offset, limit int) ([]*model.Comment, error) {
-	// SQL-запрос для получения комментариев с пагинацией
-	query := `
-		SELECT id, post_id, parent_id, content, author_id, username, have_comments, created_at
-		FROM comments
-		WHERE post_id = $1 and parent_id IS NULL 
-		ORDER BY created_at ASC
-		LIMIT $2 OFFSET $3
-	`
+// UpdateComment меняет текст комментария
+func (r *PostgresRepository) UpdateComment(ctx context.Context, commentID, editorID, content string) (*model.Comment, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
 
-	// Выполняем запрос
-	rows, err := r.db.Query(query, postID, limit, offset)
+	tx, err := r.db.BeginTxx(ctx, nil)
 	if err != nil {
-		return nil, fmt.Errorf("failed to fetch comments: %w", err)
+		return nil, wrapDBError(err, "failed to begin transaction")
 	}
-	defer rows.Close()
+	defer tx.Rollback()
 
-	// Сканируем результаты в структуру Comment
-	var comments []*model.Comment
-	for rows.Next() {
-		var comment model.Comment
-		var parentID *string
-
-		err := rows.Scan(
-			&comment.ID,
-			&comment.PostID,
-			&parentID,
-			&comment.Content,
-			&comment.AuthorID,
-			&comment.Username,
-			&comment.HaveComments,
-			&comment.CreatedAt,
-		)
-		if err != nil {
-			fmt.Printf("Error during scan: %v\n", err)
-			return nil, fmt.Errorf("failed to scan comment: %w", err)
+	var deletedAt *time.Time
+	err = tx.QueryRowContext(ctx, `SELECT deleted_at FROM comments WHERE id = $1 FOR UPDATE`, commentID).Scan(&deletedAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, NotFound("комментарий не найден")
 		}
+		return nil, wrapDBError(err, "failed to lock comment")
+	}
+	if deletedAt != nil {
+		return nil, ErrCommentDeleted
+	}
 
-		author := &model.User{
-			ID:       comment.AuthorID,
-			Username: comment.Username,
-		}
-		comment.Author = author
+	editedAt := now()
+	if err := saveCommentRevision(ctx, tx, commentID, editorID, editedAt); err != nil {
+		return nil, err
+	}
+
+	query := `
+		UPDATE comments
+		SET content = $2, edited_at = $3
+		WHERE id = $1
+		RETURNING ` + commentColumns + `
+	`
 
-		comments = append(comments, &comment)
+	comment, err := scanComment(tx.QueryRowContext(ctx, query, commentID, content, editedAt))
+	if err != nil {
+		return nil, wrapDBError(err, "failed to update comment")
 	}
 
-	// Проверяем, не возникла ли ошибка при итерации по строкам
-	if err := rows.Err(); err != nil {
-		return nil, fmt.Errorf("error during rows iteration: %w", err)
+	if err := tx.Commit(); err != nil {
+		return nil, wrapDBError(err, "failed to commit comment")
 	}
 
-	return comments, nil
+	return comment, nil
 }
 
-// GetReplies получение вложенных комментариев по id родительского коментария
-func (r *PostgresRepository) GetReplies(parentID string, offset, limit int) ([]*model.Comment, error) {
-	// SQL-запрос для получения вложенных комментариев
+// DeleteComment удаляет комментарий. Пост блокируется раньше комментария, как и при
+// вставке ответа, поэтому новый ответ не может появиться у уже удалённой строки,
+// а reply_count читается после всех завершённых вставок.
+func (r *PostgresRepository) DeleteComment(ctx context.Context, commentID, editorID string) (*model.Comment, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, wrapDBError(err, "failed to begin transaction")
+	}
+	defer tx.Rollback()
+
+	var postID string
+	err = tx.QueryRowContext(ctx, `SELECT post_id FROM comments WHERE id = $1`, commentID).Scan(&postID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, NotFound("комментарий не найден")
+		}
+		return nil, wrapDBError(err, "failed to fetch comment")
+	}
+
+	// Комментарии удалённого поста тоже можно удалять, поэтому без lockPost
+	if _, err := tx.ExecContext(ctx, `SELECT 1 FROM posts WHERE id = $1 FOR NO KEY UPDATE`, postID); err != nil {
+		return nil, wrapDBError(err, "failed to lock post")
+	}
+
 	query := `
-		SELECT id, post_id, parent_id, content, author_id, username, have_comments, created_at
+		SELECT ` + commentColumns + `
 		FROM comments
-		WHERE parent_id = $1 
-		ORDER BY created_at ASC
-		LIMIT $2 OFFSET $3
+		WHERE id = $1
+		FOR UPDATE
 	`
 
-	// Выполняем запрос
-	rows, err := r.db.Query(query, parentID, limit, offset)
+	comment, err := scanComment(tx.QueryRowContext(ctx, query, commentID))
 	if err != nil {
-		return nil, fmt.Errorf("failed to fetch replies: %w", err)
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, NotFound("комментарий не найден")
+		}
+		return nil, wrapDBError(err, "failed to fetch comment")
 	}
-	defer rows.Close()
 
-	// Сканируем результаты в структуру Comment
-	var replies []*model.Comment
-	for rows.Next() {
-		var comment model.Comment
-
-		err := rows.Scan(
-			&comment.ID,
-			&comment.PostID,
-			&comment.ParentID,
-			&comment.Content,
-			&comment.AuthorID,
-			&comment.Username,
-			&comment.HaveComments,
-			&comment.CreatedAt,
-		)
+	if comment.DeletedAt != nil {
+		return comment, nil
+	}
+
+	deletedAt := now()
+
+	if comment.ReplyCount > 0 {
+		// Ответы остаются на месте, от комментария остаётся заглушка
+		if err := saveCommentRevision(ctx, tx, comment.ID, editorID, deletedAt); err != nil {
+			return nil, err
+		}
+		_, err = tx.ExecContext(ctx, `UPDATE comments SET content = $2, deleted_at = $3 WHERE id = $1`,
+			comment.ID, model.DeletedContent, deletedAt)
 		if err != nil {
-			fmt.Printf("Error during scan: %v\n", err)
-			return nil, fmt.Errorf("failed to scan reply: %w", err)
+			return nil, wrapDBError(err, "failed to delete comment")
 		}
-
-		// Создаем объект автора
-		author := &model.User{
-			ID:       comment.AuthorID,
-			Username: comment.Username,
+	} else {
+		if _, err = tx.ExecContext(ctx, `DELETE FROM comments WHERE id = $1`, comment.ID); err != nil {
+			return nil, wrapDBError(err, "failed to delete comment")
+		}
+		if _, err = tx.ExecContext(ctx, `DELETE FROM revisions WHERE entity_id = $1`, comment.ID); err != nil {
+			return nil, wrapDBError(err, "failed to delete comment revisions")
+		}
+		if _, err = tx.ExecContext(ctx, `DELETE FROM votes WHERE target_id = $1`, comment.ID); err != nil {
+			return nil, wrapDBError(err, "failed to delete comment votes")
 		}
-		comment.Author = author
+		if _, err = tx.ExecContext(ctx, `DELETE FROM reactions WHERE target_id = $1`, comment.ID); err != nil {
+			return nil, wrapDBError(err, "failed to delete comment reactions")
+		}
+		if err := decrementCommentCounters(ctx, tx, comment.PostID, comment.ParentID); err != nil {
+			return nil, err
+		}
+	}
 
-		replies = append(replies, &comment)
+	// Закладки удаляются и у заглушки: сохранять нечего
+	if _, err = tx.ExecContext(ctx, `DELETE FROM bookmarks WHERE target_id = $1`, comment.ID); err != nil {
+		return nil, wrapDBError(err, "failed to delete comment bookmarks")
 	}
 
-	// Проверяем, не возникла ли ошибка при итерации по строкам
-	if err := rows.Err(); err != nil {
-		return nil, fmt.Errorf("error during rows iteration: %w", err)
+	if err := tx.Commit(); err != nil {
+		return nil, wrapDBError(err, "failed to commit comment deletion")
 	}
 
-	return replies, nil
+	comment.Content = model.DeletedContent
+	comment.DeletedAt = &deletedAt
+
+	return comment, nil
 }
 
-// isDuplicateKeyError проверка дупликата
-func isDuplicateKeyError(err error) bool {
-	// PostgreSQL возвращает ошибку с кодом "23505" при нарушении уникальности
-	if err, ok := err.(*pq.Error); ok {
-		return err.Code == "23505"
+// decrementCommentCounters уменьшает счётчики после удаления комментария без ответов
+func decrementCommentCounters(ctx context.Context, tx *sqlx.Tx, postID string, parentID *string) error {
+	_, err := tx.ExecContext(ctx, `
+		UPDATE posts
+		SET comment_count = comment_count - 1, have_comments = comment_count > 1
+		WHERE id = $1
+	`, postID)
+	if err != nil {
+		return wrapDBError(err, "failed to update post counters")
+	}
+
+	if parentID == nil {
+		return nil
 	}
-	return false
+
+	_, err = tx.ExecContext(ctx, `
+		UPDATE comments
+		SET reply_count = reply_count - 1, have_comments = reply_count > 1
+		WHERE id = $1
+	`, *parentID)
+	if err != nil {
+		return wrapDBError(err, "failed to update parent comment")
+	}
+
+	return nil
+}
+
+// GetCommentsByPostID получаем верхнеуровневые коментарии к посту используя пагинацию
+func (r *PostgresRepository) GetCommentsByPostID(ctx context.Context, postID string, order model.SortOrder, offset, limit int) ([]*model.Comment, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	// SQL-запрос для получения комментариев с пагинацией
+	_, orderBy := sortSQL(order, "reply_count")
+	query := `
+		SELECT ` + commentColumns + `
+		FROM comments
+		WHERE post_id = $1 and parent_id IS NULL 
+		ORDER BY ` + orderBy + `
+		LIMIT $2 OFFSET $3
+	`
+
+	// Выполняем запрос
+	rows, err := r.db.QueryContext(ctx, query, postID, limit, offset)
+	if err != nil {
+		return nil, wrapDBError(err, "failed to fetch comments")
+	}
+	defer rows.Close()
+
+	// Сканируем результаты в структуру Comment
+	var comments []*model.Comment
+	for rows.Next() {
+		comment, err := scanComment(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan comment: %w", err)
+		}
+
+		comments = append(comments, comment)
+	}
+
+	// Проверяем, не возникла ли ошибка при итерации по строкам
+	if err := rows.Err(); err != nil {
+		return nil, wrapDBError(err, "error during rows iteration")
+	}
+
+	return comments, nil
+}
+
+// GetReplies получение вложенных комментариев по id родительского коментария
+func (r *PostgresRepository) GetReplies(ctx context.Context, parentID string, order model.SortOrder, offset, limit int) ([]*model.Comment, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	// SQL-запрос для получения вложенных комментариев
+	_, orderBy := sortSQL(order, "reply_count")
+	query := `
+		SELECT ` + commentColumns + `
+		FROM comments
+		WHERE parent_id = $1 
+		ORDER BY ` + orderBy + `
+		LIMIT $2 OFFSET $3
+	`
+
+	// Выполняем запрос
+	rows, err := r.db.QueryContext(ctx, query, parentID, limit, offset)
+	if err != nil {
+		return nil, wrapDBError(err, "failed to fetch replies")
+	}
+	defer rows.Close()
+
+	// Сканируем результаты в структуру Comment
+	var replies []*model.Comment
+	for rows.Next() {
+		comment, err := scanComment(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan reply: %w", err)
+		}
+
+		replies = append(replies, comment)
+	}
+
+	// Проверяем, не возникла ли ошибка при итерации по строкам
+	if err := rows.Err(); err != nil {
+		return nil, wrapDBError(err, "error during rows iteration")
+	}
+
+	return replies, nil
+}
+
+// GetCommentByID получаем комментарий по id
+func (r *PostgresRepository) GetCommentByID(ctx context.Context, id string) (*model.Comment, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	query := `
+		SELECT ` + commentColumns + `
+		FROM comments
+		WHERE id = $1
+	`
+
+	comment, err := scanComment(r.db.QueryRowContext(ctx, query, id))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, NotFound("комментарий не найден")
+		}
+		return nil, wrapDBError(err, "failed to fetch comment")
+	}
+
+	return comment, nil
+}
+
+// GetCommentsAfter получаем комментарии поста всех уровней, созданные после курсора
+func (r *PostgresRepository) GetCommentsAfter(ctx context.Context, postID string, after model.Cursor, limit int) ([]*model.Comment, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	query := `
+		SELECT ` + commentColumns + `
+		FROM comments
+		WHERE post_id = $1 AND (created_at, id) > ($2::timestamp, $3::uuid)
+		ORDER BY created_at ASC, id ASC
+		LIMIT $4
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, postID, after.CreatedAt.UTC(), after.ID, limit)
+	if err != nil {
+		return nil, wrapDBError(err, "failed to fetch comments after cursor")
+	}
+	defer rows.Close()
+
+	var comments []*model.Comment
+	for rows.Next() {
+		comment, err := scanComment(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan comment: %w", err)
+		}
+
+		comments = append(comments, comment)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, wrapDBError(err, "error during rows iteration")
+	}
+
+	return comments, nil
+}
+
+// GetCommentsEditedAfter возвращает комментарии поста, изменённые после курсора
+// (индекс idx_comments_post_edited)
+func (r *PostgresRepository) GetCommentsEditedAfter(ctx context.Context, postID string, after model.Cursor, limit int) ([]*model.Comment, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	query := `
+		SELECT ` + commentColumns + `
+		FROM comments
+		WHERE post_id = $1 AND edited_at IS NOT NULL AND deleted_at IS NULL
+			AND (edited_at, id) > ($2::timestamp, $3::uuid)
+		ORDER BY edited_at ASC, id ASC
+		LIMIT $4
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, postID, after.CreatedAt.UTC(), after.ID, limit)
+	if err != nil {
+		return nil, wrapDBError(err, "failed to fetch comments edited after cursor")
+	}
+	defer rows.Close()
+
+	var comments []*model.Comment
+	for rows.Next() {
+		comment, err := scanComment(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan comment: %w", err)
+		}
+
+		comments = append(comments, comment)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, wrapDBError(err, "error during rows iteration")
+	}
+
+	return comments, nil
+}
+
+// GetPostsPage получаем страницу постов в порядке order
+func (r *PostgresRepository) GetPostsPage(ctx context.Context, order model.SortOrder, filter model.PostFilter, first int, after *model.Cursor) (*model.Page[*model.Post], error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	// Условие по кортежу (created_at, id) использует индекс idx_posts_created
+	// вместо сканирования OFFSET
+	rank, orderBy := sortSQL(order, "comment_count")
+	query := `
+		SELECT ` + postColumns + `
+		FROM posts
+		WHERE deleted_at IS NULL`
+	filterCond, args := postFilterSQL(filter, nil)
+	query += filterCond
+	// Число постов считается с теми же условиями фильтра
+	countArgs := args
+	if after != nil {
+		var cond string
+		cond, args = keysetSQL(order, rank, "posts", *after, args)
+		query += ` AND ` + cond
+	}
+	query += fmt.Sprintf(` ORDER BY %s LIMIT $%d`, orderBy, len(args)+1)
+	// Лишняя запись показывает, есть ли следующая страница
+	args = append(args, first+1)
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, wrapDBError(err, "failed to fetch posts page")
+	}
+	defer rows.Close()
+
+	var posts []*model.Post
+	for rows.Next() {
+		post, err := scanPost(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan post: %w", err)
+		}
+		posts = append(posts, post)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, wrapDBError(err, "error iterating over posts")
+	}
+
+	page := &model.Page[*model.Post]{Items: posts}
+	if len(posts) > first {
+		page.Items = posts[:first]
+		page.HasNextPage = true
+	}
+
+	err = r.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM posts WHERE deleted_at IS NULL`+filterCond, countArgs...).Scan(&page.TotalCount)
+	if err != nil {
+		return nil, wrapDBError(err, "failed to count posts")
+	}
+
+	return page, nil
+}
+
+// GetCommentsPage получаем страницу комментариев верхнего уровня в порядке order
+func (r *PostgresRepository) GetCommentsPage(ctx context.Context, postID string, order model.SortOrder, first int, after *model.Cursor) (*model.Page[*model.Comment], error) {
+	return r.commentsPage(ctx, "post_id = $1 AND parent_id IS NULL", postID, order, first, after)
+}
+
+// GetRepliesPage получаем страницу ответов на комментарий в порядке order
+func (r *PostgresRepository) GetRepliesPage(ctx context.Context, parentID string, order model.SortOrder, first int, after *model.Cursor) (*model.Page[*model.Comment], error) {
+	return r.commentsPage(ctx, "parent_id = $1", parentID, order, first, after)
+}
+
+// commentsPage выбирает страницу комментариев по условию filter с параметром $1
+func (r *PostgresRepository) commentsPage(ctx context.Context, filter string, id string, order model.SortOrder, first int, after *model.Cursor) (*model.Page[*model.Comment], error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	rank, orderBy := sortSQL(order, "reply_count")
+
+	query := `
+		SELECT ` + commentColumns + `
+		FROM comments
+		WHERE ` + filter
+	args := []interface{}{id}
+	if after != nil {
+		var cond string
+		cond, args = keysetSQL(order, rank, "comments", *after, args)
+		query += ` AND ` + cond
+	}
+	query += fmt.Sprintf(` ORDER BY %s LIMIT $%d`, orderBy, len(args)+1)
+	// Лишняя запись показывает, есть ли следующая страница
+	args = append(args, first+1)
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, wrapDBError(err, "failed to fetch comments page")
+	}
+	defer rows.Close()
+
+	var comments []*model.Comment
+	for rows.Next() {
+		comment, err := scanComment(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan comment: %w", err)
+		}
+
+		comments = append(comments, comment)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, wrapDBError(err, "error during rows iteration")
+	}
+
+	page := &model.Page[*model.Comment]{Items: comments}
+	if len(comments) > first {
+		page.Items = comments[:first]
+		page.HasNextPage = true
+	}
+
+	err = r.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM comments WHERE `+filter, id).Scan(&page.TotalCount)
+	if err != nil {
+		return nil, wrapDBError(err, "failed to count comments")
+	}
+
+	return page, nil
+}
+
+// GetCommentsByPostIDs получаем страницы комментариев верхнего уровня сразу для нескольких постов
+func (r *PostgresRepository) GetCommentsByPostIDs(ctx context.Context, postIDs []string, order model.SortOrder, offset, limit int) (map[string][]*model.Comment, error) {
+	return r.commentsByKeys(ctx, "post_id", "parent_id IS NULL", postIDs, order, offset, limit)
+}
+
+// GetRepliesByParentIDs получаем страницы ответов сразу для нескольких комментариев
+func (r *PostgresRepository) GetRepliesByParentIDs(ctx context.Context, parentIDs []string, order model.SortOrder, offset, limit int) (map[string][]*model.Comment, error) {
+	return r.commentsByKeys(ctx, "parent_id", "TRUE", parentIDs, order, offset, limit)
+}
+
+// commentsByKeys одним запросом выбирает страницу offset/limit для каждого
+// значения колонки key. Нумерация ROW_NUMBER идёт внутри каждой группы.
+func (r *PostgresRepository) commentsByKeys(ctx context.Context, key, filter string, ids []string, order model.SortOrder, offset, limit int) (map[string][]*model.Comment, error) {
+	result := make(map[string][]*model.Comment, len(ids))
+	if len(ids) == 0 || limit <= 0 || offset < 0 {
+		return result, nil
+	}
+
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	_, orderBy := sortSQL(order, "reply_count")
+	query := `
+		SELECT ` + commentColumns + `
+		FROM (
+			SELECT ` + commentColumns + `,
+				ROW_NUMBER() OVER (PARTITION BY ` + key + ` ORDER BY ` + orderBy + `) AS rn
+			FROM comments
+			WHERE ` + key + ` = ANY($1::uuid[]) AND ` + filter + `
+		) numbered
+		WHERE rn > $2 AND rn <= $2 + $3
+		ORDER BY rn
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, pq.Array(ids), offset, limit)
+	if err != nil {
+		return nil, wrapDBError(err, "failed to fetch comments batch")
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		comment, err := scanComment(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan comment: %w", err)
+		}
+
+		group := comment.PostID
+		if key == "parent_id" {
+			group = *comment.ParentID
+		}
+		result[group] = append(result[group], comment)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, wrapDBError(err, "error during rows iteration")
+	}
+
+	return result, nil
+}
+
+// GetCommentTree получаем дерево комментариев поста одним рекурсивным запросом.
+// LATERAL с LIMIT отбирает не больше perLevelLimit ответов на каждый комментарий,
+// поэтому ветки за пределами лимита не читаются вовсе.
+func (r *PostgresRepository) GetCommentTree(ctx context.Context, postID string, maxDepth, perLevelLimit int) (*model.CommentTree, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	query := `
+		WITH RECURSIVE tree AS (
+			(
+				SELECT id, post_id, parent_id, content, author_id, username, have_comments, created_at, reply_count, edited_at, deleted_at, score, upvotes, downvotes, 0 AS depth
+				FROM comments
+				WHERE post_id = $1 AND parent_id IS NULL
+				ORDER BY created_at ASC, id ASC
+				LIMIT $3
+			)
+			UNION ALL
+			SELECT child.id, child.post_id, child.parent_id, child.content, child.author_id,
+				child.username, child.have_comments, child.created_at, child.reply_count, child.edited_at, child.deleted_at, child.score, child.upvotes, child.downvotes,
+				tree.depth + 1
+			FROM tree
+			CROSS JOIN LATERAL (
+				SELECT id, post_id, parent_id, content, author_id, username, have_comments, created_at, reply_count,
+					edited_at, deleted_at, score, upvotes, downvotes
+				FROM comments
+				WHERE parent_id = tree.id
+				ORDER BY created_at ASC, id ASC
+				LIMIT $3
+			) child
+			WHERE tree.depth < $2
+		)
+		SELECT ` + commentColumns + `
+		FROM tree
+		ORDER BY depth ASC, created_at ASC, id ASC
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, postID, maxDepth, perLevelLimit)
+	if err != nil {
+		return nil, wrapDBError(err, "failed to fetch comment tree")
+	}
+	defer rows.Close()
+
+	tree := &model.CommentTree{}
+	nodes := make(map[string]*model.CommentNode)
+
+	// Строки идут по уровням, поэтому родитель всегда уже в nodes
+	for rows.Next() {
+		comment, err := scanComment(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan comment: %w", err)
+		}
+
+		node := model.CommentNode{Comment: comment, Depth: comment.Depth}
+		// Загруженные ответы вычитаются по мере их появления
+		node.TruncatedReplies = comment.ReplyCount
+		nodes[comment.ID] = &node
+
+		if comment.ParentID == nil {
+			tree.Roots = append(tree.Roots, &node)
+			continue
+		}
+		if parent, ok := nodes[*comment.ParentID]; ok {
+			parent.Children = append(parent.Children, &node)
+			parent.TruncatedReplies--
+		}
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, wrapDBError(err, "error during rows iteration")
+	}
+
+	var rootsCount int
+	err = r.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM comments WHERE post_id = $1 AND parent_id IS NULL`, postID).Scan(&rootsCount)
+	if err != nil {
+		return nil, wrapDBError(err, "failed to count comments")
+	}
+	tree.TruncatedRoots = rootsCount - len(tree.Roots)
+
+	return tree, nil
+}
+
+// GetThreadPage получаем страницу комментариев поста в порядке обхода ветки в глубину.
+// Пути сравниваются побайтово (COLLATE "C"), как и в индексе idx_comments_thread.
+func (r *PostgresRepository) GetThreadPage(ctx context.Context, postID string, first int, after string) (*model.Page[*model.Comment], error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	query := `
+		SELECT ` + commentColumns + `, path
+		FROM comments
+		WHERE post_id = $1 AND path COLLATE "C" > $2
+		ORDER BY path COLLATE "C" ASC
+		LIMIT $3
+	`
+
+	// Лишняя запись показывает, есть ли следующая страница
+	rows, err := r.db.QueryContext(ctx, query, postID, after, first+1)
+	if err != nil {
+		return nil, wrapDBError(err, "failed to fetch thread")
+	}
+	defer rows.Close()
+
+	var comments []*model.Comment
+	for rows.Next() {
+		var path string
+		comment, err := scanComment(rows, &path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan comment: %w", err)
+		}
+		comment.Path = path
+		comments = append(comments, comment)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, wrapDBError(err, "error during rows iteration")
+	}
+
+	page := &model.Page[*model.Comment]{Items: comments}
+	if len(comments) > first {
+		page.Items = comments[:first]
+		page.HasNextPage = true
+	}
+
+	err = r.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM comments WHERE post_id = $1`, postID).Scan(&page.TotalCount)
+	if err != nil {
+		return nil, wrapDBError(err, "failed to count comments")
+	}
+
+	return page, nil
+}
+
+// savePostRevision сохраняет текущие заголовок и текст поста как ревизию
+func savePostRevision(ctx context.Context, tx *sqlx.Tx, postID, editorID string, at time.Time) error {
+	_, err := tx.ExecContext(ctx, `
+		INSERT INTO revisions (id, entity_id, title, content, editor_id, created_at)
+		SELECT $1, id, title, content, $2, $3
+		FROM posts
+		WHERE id = $4
+	`, uuid.New().String(), editorID, at, postID)
+	if err != nil {
+		return wrapDBError(err, "failed to save post revision")
+	}
+
+	return nil
+}
+
+// saveCommentRevision сохраняет текущий текст комментария как ревизию
+func saveCommentRevision(ctx context.Context, tx *sqlx.Tx, commentID, editorID string, at time.Time) error {
+	_, err := tx.ExecContext(ctx, `
+		INSERT INTO revisions (id, entity_id, title, content, editor_id, created_at)
+		SELECT $1, id, NULL, content, $2, $3
+		FROM comments
+		WHERE id = $4
+	`, uuid.New().String(), editorID, at, commentID)
+	if err != nil {
+		return wrapDBError(err, "failed to save comment revision")
+	}
+
+	return nil
+}
+
+// GetRevisions получаем прежние версии поста или комментария от старых к новым
+func (r *PostgresRepository) GetRevisions(ctx context.Context, entityID string) ([]*model.Revision, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	query := `
+		SELECT id, entity_id, title, content, editor_id, created_at
+		FROM revisions
+		WHERE entity_id = $1
+		ORDER BY created_at ASC, id ASC
+	`
+
+	revisions := []*model.Revision{}
+	if err := r.db.SelectContext(ctx, &revisions, query, entityID); err != nil {
+		return nil, wrapDBError(err, "failed to fetch revisions")
+	}
+
+	return revisions, nil
+}
+
+// GetRevisionByID получаем ревизию по id
+func (r *PostgresRepository) GetRevisionByID(ctx context.Context, id string) (*model.Revision, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	query := `
+		SELECT id, entity_id, title, content, editor_id, created_at
+		FROM revisions
+		WHERE id = $1
+	`
+
+	var revision model.Revision
+	if err := r.db.GetContext(ctx, &revision, query, id); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, NotFound("ревизия не найдена")
+		}
+		return nil, wrapDBError(err, "failed to fetch revision")
+	}
+
+	return &revision, nil
+}
+
+// sortSQL возвращает выражение веса (для порядков по времени – "") и ORDER BY
+// для порядка order. counter – столбец числа ответов: comment_count у постов,
+// reply_count у комментариев. Выражения повторяют model.SortOrder.
+func sortSQL(order model.SortOrder, counter string) (rank string, orderBy string) {
+	switch order {
+	case model.OrderOldest:
+		return "", "created_at ASC, id ASC"
+	case model.OrderTop:
+		rank = "score"
+	case model.OrderMostReplied:
+		rank = counter
+	case model.OrderHot:
+		// model.HotRank: знак и десятичный логарифм активности плюс возраст в периодах HotPeriod
+		activity := "(score + " + counter + ")::float8"
+		rank = fmt.Sprintf("(SIGN(%[1]s) * LOG(GREATEST(ABS(%[1]s), 1)) + EXTRACT(EPOCH FROM created_at)::float8 / %[2]d)",
+			activity, model.HotPeriod)
+	default:
+		return "", "created_at DESC, id DESC"
+	}
+	return rank, rank + " DESC, created_at DESC, id DESC"
+}
+
+// keysetSQL возвращает условие "строго после курсора" для порядка order и
+// добавляет его параметры к args. Если задана таблица table, вес курсора
+// ранжированного порядка берётся из строки курсора тем же выражением rank, что
+// и в ORDER BY: вес HOT, посчитанный в Go, расходится с SQL в последних знаках,
+// и записи на границе страниц повторялись бы или пропадали. Вес из курсора
+// остаётся запасным значением, если строки уже нет
+func keysetSQL(order model.SortOrder, rank, table string, after model.Cursor, args []interface{}) (string, []interface{}) {
+	n := len(args) + 1
+	switch {
+	case order == model.OrderOldest:
+		return fmt.Sprintf(`(created_at, id) > ($%d::timestamp, $%d::uuid)`, n, n+1),
+			append(args, after.CreatedAt.UTC(), after.ID)
+	case order.Ranked():
+		bound := fmt.Sprintf(`$%d::float8`, n)
+		if table != "" {
+			bound = fmt.Sprintf(`COALESCE((SELECT %s FROM %s WHERE id = $%d::uuid), %s)`, rank, table, n+2, bound)
+		}
+		return fmt.Sprintf(`(%s, created_at, id) < (%s, $%d::timestamp, $%d::uuid)`, rank, bound, n+1, n+2),
+			append(args, after.Rank, after.CreatedAt.UTC(), after.ID)
+	default:
+		return fmt.Sprintf(`(created_at, id) < ($%d::timestamp, $%d::uuid)`, n, n+1),
+			append(args, after.CreatedAt.UTC(), after.ID)
+	}
+}
+
+// searchConfig – конфигурация текстового поиска столбцов search_vector
+// (см. миграцию): кириллица – русский стеммер Snowball, латиница – английский
+const searchConfig = "russian"
+
+// searchHeadlineOptions – параметры ts_headline, совпадающие с search.Highlight
+var searchHeadlineOptions = fmt.Sprintf(`StartSel=%s, StopSel=%s, MaxWords=%d, MinWords=%d`,
+	search.MarkStart, search.MarkStop, search.SnippetWords, search.SnippetWords/2)
+
+// Search ищет посты и комментарии по столбцам search_vector (GIN-индексы
+// idx_posts_search и idx_comments_search). Вес – ts_rank, фрагменты
+// ts_headline строятся только для выданной страницы
+func (r *PostgresRepository) Search(ctx context.Context, query string, kind model.SearchKind, first int, after *model.Cursor) (*model.Page[*model.SearchHit], error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	var parts []string
+	if kind != model.SearchComments {
+		parts = append(parts, `
+			SELECT 'POST' AS kind, p.id, ts_rank(p.search_vector, q.query)::float8 AS rank, p.created_at,
+				p.title || E'\n' || p.content AS body
+			FROM posts p, q
+			WHERE p.search_vector @@ q.query AND p.deleted_at IS NULL`)
+	}
+	if kind != model.SearchPosts {
+		parts = append(parts, `
+			SELECT 'COMMENT', c.id, ts_rank(c.search_vector, q.query)::float8, c.created_at, c.content
+			FROM comments c JOIN posts p ON p.id = c.post_id, q
+			WHERE c.search_vector @@ q.query AND c.deleted_at IS NULL AND p.deleted_at IS NULL`)
+	}
+	hits := `
+		WITH q AS (SELECT websearch_to_tsquery('` + searchConfig + `', $1) AS query),
+		hits AS (` + strings.Join(parts, " UNION ALL ") + `)`
+
+	args := []interface{}{query, searchHeadlineOptions}
+	filter := "TRUE"
+	if after != nil {
+		// Вес ts_rank приходит в курсор из базы без пересчёта
+		filter, args = keysetSQL(model.OrderTop, "rank", "", *after, args)
+	}
+	pageQuery := hits + fmt.Sprintf(`
+		SELECT h.kind, h.id, h.rank, ts_headline('%s', h.body, q.query, $2)
+		FROM (
+			SELECT * FROM hits
+			WHERE %s
+			ORDER BY rank DESC, created_at DESC, id DESC
+			LIMIT $%d
+		) h, q
+		ORDER BY h.rank DESC, h.created_at DESC, h.id DESC
+	`, searchConfig, filter, len(args)+1)
+	// Лишняя запись показывает, есть ли следующая страница
+	args = append(args, first+1)
+
+	rows, err := r.db.QueryContext(ctx, pageQuery, args...)
+	if err != nil {
+		return nil, wrapDBError(err, "failed to search")
+	}
+	defer rows.Close()
+
+	type found struct {
+		kind, id string
+		rank     float64
+		snippet  string
+	}
+	var (
+		items               []found
+		postIDs, commentIDs []string
+	)
+	for rows.Next() {
+		var f found
+		if err := rows.Scan(&f.kind, &f.id, &f.rank, &f.snippet); err != nil {
+			return nil, fmt.Errorf("failed to scan search hit: %w", err)
+		}
+		items = append(items, f)
+		if f.kind == "POST" {
+			postIDs = append(postIDs, f.id)
+		} else {
+			commentIDs = append(commentIDs, f.id)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, wrapDBError(err, "error iterating over search hits")
+	}
+
+	posts, err := r.postsByIDs(ctx, postIDs)
+	if err != nil {
+		return nil, err
+	}
+	comments, err := r.commentsByIDs(ctx, commentIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	page := &model.Page[*model.SearchHit]{Items: make([]*model.SearchHit, 0, len(items))}
+	for _, f := range items {
+		hit := &model.SearchHit{Post: posts[f.id], Comment: comments[f.id], Rank: f.rank, Snippet: f.snippet}
+		// Запись удалили между запросами
+		if hit.Post == nil && hit.Comment == nil {
+			continue
+		}
+		page.Items = append(page.Items, hit)
+	}
+	if len(items) > first {
+		page.Items = page.Items[:min(first, len(page.Items))]
+		page.HasNextPage = true
+	}
+
+	if err := r.db.QueryRowContext(ctx, hits+` SELECT COUNT(*) FROM hits`, query).Scan(&page.TotalCount); err != nil {
+		return nil, wrapDBError(err, "failed to count search hits")
+	}
+
+	return page, nil
+}
+
+// postsByIDs загружает посты по списку ID
+func (r *PostgresRepository) postsByIDs(ctx context.Context, ids []string) (map[string]*model.Post, error) {
+	posts := make(map[string]*model.Post, len(ids))
+	if len(ids) == 0 {
+		return posts, nil
+	}
+
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT `+postColumns+`
+		FROM posts
+		WHERE id = ANY($1::uuid[])
+	`, pq.Array(ids))
+	if err != nil {
+		return nil, wrapDBError(err, "failed to fetch posts")
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		post, err := scanPost(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan post: %w", err)
+		}
+		posts[post.ID] = post
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, wrapDBError(err, "error iterating over posts")
+	}
+
+	return posts, nil
+}
+
+// commentsByIDs загружает комментарии по списку ID
+func (r *PostgresRepository) commentsByIDs(ctx context.Context, ids []string) (map[string]*model.Comment, error) {
+	comments := make(map[string]*model.Comment, len(ids))
+	if len(ids) == 0 {
+		return comments, nil
+	}
+
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT `+commentColumns+`
+		FROM comments
+		WHERE id = ANY($1::uuid[])
+	`, pq.Array(ids))
+	if err != nil {
+		return nil, wrapDBError(err, "failed to fetch comments")
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		comment, err := scanComment(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan comment: %w", err)
+		}
+
+		comments[comment.ID] = comment
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, wrapDBError(err, "error during rows iteration")
+	}
+
+	return comments, nil
+}
+
+// Vote ставит или отменяет голос. Строка поста или комментария блокируется
+// до конца транзакции, поэтому голоса за одну запись идут по очереди, а счётчики
+// меняются приращениями в той же транзакции, что и голос
+func (r *PostgresRepository) Vote(ctx context.Context, userID, targetID string, value int) (*model.Votable, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, wrapDBError(err, "failed to begin transaction")
+	}
+	defer tx.Rollback()
+
+	table, err := lockVoteTarget(ctx, tx, targetID)
+	if err != nil {
+		return nil, err
+	}
+
+	var old int
+	err = tx.QueryRowContext(ctx, `SELECT value FROM votes WHERE user_id = $1 AND target_id = $2`, userID, targetID).Scan(&old)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return nil, wrapDBError(err, "failed to fetch vote")
+	}
+
+	if old != value {
+		if value == 0 {
+			_, err = tx.ExecContext(ctx, `DELETE FROM votes WHERE user_id = $1 AND target_id = $2`, userID, targetID)
+		} else {
+			_, err = tx.ExecContext(ctx, `
+				INSERT INTO votes (user_id, target_id, value, created_at)
+				VALUES ($1, $2, $3, $4)
+				ON CONFLICT (user_id, target_id) DO UPDATE SET value = EXCLUDED.value, created_at = EXCLUDED.created_at
+			`, userID, targetID, value, now())
+		}
+		if err != nil {
+			return nil, wrapDBError(err, "failed to save vote")
+		}
+
+		up, down := voteDelta(old, value)
+		_, err = tx.ExecContext(ctx, `
+			UPDATE `+table+`
+			SET upvotes = upvotes + $2, downvotes = downvotes + $3, score = score + $2 - $3
+			WHERE id = $1
+		`, targetID, up, down)
+		if err != nil {
+			return nil, wrapDBError(err, "failed to update vote counters")
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, wrapDBError(err, "failed to commit vote")
+	}
+
+	if table == "posts" {
+		post, err := r.GetPostByID(ctx, targetID)
+		if err != nil {
+			return nil, err
+		}
+		return &model.Votable{Post: post}, nil
+	}
+
+	comment, err := r.GetCommentByID(ctx, targetID)
+	if err != nil {
+		return nil, err
+	}
+	return &model.Votable{Comment: comment}, nil
+}
+
+// lockVoteTarget находит пост или комментарий targetID, блокирует его строку
+// и возвращает имя таблицы
+func lockVoteTarget(ctx context.Context, tx *sqlx.Tx, targetID string) (string, error) {
+	var deletedAt *time.Time
+
+	err := tx.QueryRowContext(ctx, `SELECT deleted_at FROM posts WHERE id = $1 FOR NO KEY UPDATE`, targetID).Scan(&deletedAt)
+	if err == nil {
+		if deletedAt != nil {
+			return "", ErrPostDeleted
+		}
+		return "posts", nil
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return "", wrapDBError(err, "failed to lock post")
+	}
+
+	err = tx.QueryRowContext(ctx, `SELECT deleted_at FROM comments WHERE id = $1 FOR NO KEY UPDATE`, targetID).Scan(&deletedAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return "", NotFound("пост или комментарий не найден")
+		}
+		return "", wrapDBError(err, "failed to lock comment")
+	}
+	if deletedAt != nil {
+		return "", ErrCommentDeleted
+	}
+
+	return "comments", nil
+}
+
+// GetVotes возвращает голоса пользователя за записи targetIDs
+func (r *PostgresRepository) GetVotes(ctx context.Context, userID string, targetIDs []string) (map[string]int, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	votes := make(map[string]int, len(targetIDs))
+	if len(targetIDs) == 0 {
+		return votes, nil
+	}
+
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT target_id, value
+		FROM votes
+		WHERE user_id = $1 AND target_id = ANY($2::uuid[])
+	`, userID, pq.Array(targetIDs))
+	if err != nil {
+		return nil, wrapDBError(err, "failed to fetch votes")
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			targetID string
+			value    int
+		)
+		if err := rows.Scan(&targetID, &value); err != nil {
+			return nil, fmt.Errorf("failed to scan vote: %w", err)
+		}
+		votes[targetID] = value
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, wrapDBError(err, "error iterating over votes")
+	}
+
+	return votes, nil
+}
+
+// AddReaction добавляет реакцию пользователя, если её ещё нет
+func (r *PostgresRepository) AddReaction(ctx context.Context, userID, targetID, emoji string) (*model.ReactionChange, error) {
+	return r.changeReaction(ctx, userID, targetID, `
+		INSERT INTO reactions (user_id, target_id, emoji, post_id, created_at)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (user_id, target_id, emoji) DO NOTHING
+	`, emoji, true)
+}
+
+// RemoveReaction снимает реакцию пользователя, если она есть
+func (r *PostgresRepository) RemoveReaction(ctx context.Context, userID, targetID, emoji string) (*model.ReactionChange, error) {
+	return r.changeReaction(ctx, userID, targetID, `
+		DELETE FROM reactions WHERE user_id = $1 AND target_id = $2 AND emoji = $3
+	`, emoji, false)
+}
+
+// changeReaction выполняет query (вставку или удаление реакции) и считает
+// реакции в той же транзакции. Строка записи блокируется FOR KEY SHARE, чтобы
+// комментарий не удалили окончательно, пока на него ставят реакцию
+func (r *PostgresRepository) changeReaction(ctx context.Context, userID, targetID, query, emoji string, add bool) (*model.ReactionChange, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, wrapDBError(err, "failed to begin transaction")
+	}
+	defer tx.Rollback()
+
+	postID, err := shareLockTarget(ctx, tx, targetID)
+	if err != nil {
+		return nil, err
+	}
+
+	var res sql.Result
+	if add {
+		res, err = tx.ExecContext(ctx, query, userID, targetID, emoji, postID, now())
+	} else {
+		res, err = tx.ExecContext(ctx, query, userID, targetID, emoji)
+	}
+	if err != nil {
+		return nil, wrapDBError(err, "failed to save reaction")
+	}
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return nil, wrapDBError(err, "failed to save reaction")
+	}
+
+	counts, err := reactionCounts(ctx, tx, userID, []string{targetID})
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, wrapDBError(err, "failed to commit reaction")
+	}
+
+	reactions := counts[targetID]
+	if reactions == nil {
+		reactions = []*model.ReactionCount{}
+	}
+
+	return &model.ReactionChange{
+		PostID:    postID,
+		TargetID:  targetID,
+		Changed:   affected > 0,
+		Reactions: reactions,
+	}, nil
+}
+
+// shareLockTarget находит пост или комментарий targetID, блокирует его строку
+// от окончательного удаления (FOR KEY SHARE) и возвращает ID поста. Удалённые
+// записи: ErrPostDeleted, ErrCommentDeleted
+func shareLockTarget(ctx context.Context, tx *sqlx.Tx, targetID string) (string, error) {
+	var deletedAt *time.Time
+
+	err := tx.QueryRowContext(ctx, `SELECT deleted_at FROM posts WHERE id = $1 FOR KEY SHARE`, targetID).Scan(&deletedAt)
+	if err == nil {
+		if deletedAt != nil {
+			return "", ErrPostDeleted
+		}
+		return targetID, nil
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return "", wrapDBError(err, "failed to lock post")
+	}
+
+	var postID string
+	err = tx.QueryRowContext(ctx, `SELECT post_id, deleted_at FROM comments WHERE id = $1 FOR KEY SHARE`, targetID).Scan(&postID, &deletedAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return "", NotFound("пост или комментарий не найден")
+		}
+		return "", wrapDBError(err, "failed to lock comment")
+	}
+	if deletedAt != nil {
+		return "", ErrCommentDeleted
+	}
+
+	return postID, nil
+}
+
+// GetReactions возвращает реакции на записи targetIDs
+func (r *PostgresRepository) GetReactions(ctx context.Context, userID string, targetIDs []string) (map[string][]*model.ReactionCount, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	return reactionCounts(ctx, r.db, userID, targetIDs)
+}
+
+// reactionCounts считает реакции на записи targetIDs по видам в порядке
+// первой реакции каждого вида
+func reactionCounts(ctx context.Context, q sqlx.QueryerContext, userID string, targetIDs []string) (map[string][]*model.ReactionCount, error) {
+	reactions := make(map[string][]*model.ReactionCount, len(targetIDs))
+	if len(targetIDs) == 0 {
+		return reactions, nil
+	}
+
+	var viewer *string
+	if userID != "" {
+		viewer = &userID
+	}
+
+	rows, err := q.QueryContext(ctx, `
+		SELECT target_id, emoji, COUNT(*), COALESCE(BOOL_OR(user_id = $2::uuid), FALSE)
+		FROM reactions
+		WHERE target_id = ANY($1::uuid[])
+		GROUP BY target_id, emoji
+		ORDER BY target_id, MIN(created_at), emoji
+	`, pq.Array(targetIDs), viewer)
+	if err != nil {
+		return nil, wrapDBError(err, "failed to fetch reactions")
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			targetID string
+			count    model.ReactionCount
+		)
+		if err := rows.Scan(&targetID, &count.Emoji, &count.Count, &count.ViewerReacted); err != nil {
+			return nil, fmt.Errorf("failed to scan reaction: %w", err)
+		}
+		reactions[targetID] = append(reactions[targetID], &count)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, wrapDBError(err, "error iterating over reactions")
+	}
+
+	return reactions, nil
+}
+
+// postFilterSQL возвращает условия фильтра ленты (с ведущим AND) и добавляет
+// их параметры к args. Отбор по тегам использует GIN-индекс idx_posts_tags
+func postFilterSQL(filter model.PostFilter, args []interface{}) (string, []interface{}) {
+	var cond string
+	if filter.ForumID != "" {
+		args = append(args, filter.ForumID)
+		cond += fmt.Sprintf(` AND forum_id = $%d::uuid`, len(args))
+	}
+	if len(filter.Tags) > 0 {
+		args = append(args, pq.Array(filter.Tags))
+		cond += fmt.Sprintf(` AND tags @> $%d::text[]`, len(args))
+	}
+	return cond, args
+}
+
+// postTags возвращает теги поста для вставки: столбец tags не допускает NULL
+func postTags(post *model.Post) []string {
+	if post.Tags == nil {
+		return []string{}
+	}
+	return post.Tags
+}
+
+// CreateForum добавляет раздел в конец списка
+func (r *PostgresRepository) CreateForum(ctx context.Context, forum *model.Forum) error {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	forum.CreatedAt = now()
+
+	// Позиция считается в том же запросе; одновременные вставки могут получить
+	// одинаковую позицию, тогда порядок между ними задают created_at и id
+	err := r.db.QueryRowContext(ctx, `
+		INSERT INTO forums (id, name, description, position, created_at)
+		SELECT $1, $2, $3, COALESCE(MAX(position), 0) + 1, $4 FROM forums
+		RETURNING position
+	`, forum.ID, forum.Name, forum.Description, forum.CreatedAt).Scan(&forum.Position)
+	if err != nil {
+		if isDuplicateKeyError(err) {
+			return Conflict("раздел с таким названием уже существует")
+		}
+		return wrapDBError(err, "failed to create forum")
+	}
+
+	return nil
+}
+
+// GetForums возвращает разделы по Position
+func (r *PostgresRepository) GetForums(ctx context.Context) ([]*model.Forum, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	return selectForums(ctx, r.db)
+}
+
+// selectForums читает все разделы по Position
+func selectForums(ctx context.Context, q sqlx.QueryerContext) ([]*model.Forum, error) {
+	forums := []*model.Forum{}
+	err := sqlx.SelectContext(ctx, q, &forums, `
+		SELECT id, name, description, position, created_at
+		FROM forums
+		ORDER BY position, created_at, id
+	`)
+	if err != nil {
+		return nil, wrapDBError(err, "failed to fetch forums")
+	}
+
+	return forums, nil
+}
+
+// GetForumByID возвращает раздел по ID
+func (r *PostgresRepository) GetForumByID(ctx context.Context, id string) (*model.Forum, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	var forum model.Forum
+	err := r.db.GetContext(ctx, &forum, `
+		SELECT id, name, description, position, created_at
+		FROM forums
+		WHERE id = $1
+	`, id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, NotFound("раздел не найден")
+		}
+		return nil, wrapDBError(err, "failed to fetch forum")
+	}
+
+	return &forum, nil
+}
+
+// ReorderForums расставляет разделы в порядке ids. Строки разделов
+// блокируются, чтобы проверка полного списка и обновление шли согласованно
+func (r *PostgresRepository) ReorderForums(ctx context.Context, ids []string) ([]*model.Forum, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, wrapDBError(err, "failed to begin transaction")
+	}
+	defer tx.Rollback()
+
+	var existing []string
+	if err := tx.SelectContext(ctx, &existing, `SELECT id FROM forums FOR UPDATE`); err != nil {
+		return nil, wrapDBError(err, "failed to lock forums")
+	}
+
+	known := make(map[string]bool, len(existing))
+	for _, id := range existing {
+		known[id] = true
+	}
+	if err := checkForumOrder(ids, len(existing), func(id string) bool { return known[id] }); err != nil {
+		return nil, err
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		UPDATE forums f
+		SET position = o.position
+		FROM unnest($1::uuid[]) WITH ORDINALITY AS o(id, position)
+		WHERE f.id = o.id
+	`, pq.Array(ids))
+	if err != nil {
+		return nil, wrapDBError(err, "failed to reorder forums")
+	}
+
+	forums, err := selectForums(ctx, tx)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, wrapDBError(err, "failed to commit forums order")
+	}
+
+	return forums, nil
+}
+
+// GetTags считает теги неудалённых постов
+func (r *PostgresRepository) GetTags(ctx context.Context, forumID string, first int) ([]*model.Tag, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	cond, args := postFilterSQL(model.PostFilter{ForumID: forumID}, nil)
+	args = append(args, first)
+
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT tag, COUNT(*)
+		FROM posts, unnest(tags) AS tag
+		WHERE deleted_at IS NULL`+cond+`
+		GROUP BY tag
+		ORDER BY COUNT(*) DESC, tag
+		LIMIT $`+fmt.Sprint(len(args)), args...)
+	if err != nil {
+		return nil, wrapDBError(err, "failed to fetch tags")
+	}
+	defer rows.Close()
+
+	tags := []*model.Tag{}
+	for rows.Next() {
+		var tag model.Tag
+		if err := rows.Scan(&tag.Name, &tag.PostCount); err != nil {
+			return nil, fmt.Errorf("failed to scan tag: %w", err)
+		}
+		tags = append(tags, &tag)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, wrapDBError(err, "error iterating over tags")
+	}
+
+	return tags, nil
+}
+
+// AddBookmark сохраняет запись в закладки пользователя. Блокировка строки
+// записи не даёт удалить её, пока закладка не сохранена. Строка поста
+// блокируется FOR SHARE: DeletePost ждёт сохранения закладки и удаляет её
+// вместе с закладками на комментарии поста
+func (r *PostgresRepository) AddBookmark(ctx context.Context, userID, targetID string) (*model.Bookmark, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, wrapDBError(err, "failed to begin transaction")
+	}
+	defer tx.Rollback()
+
+	postID, err := shareLockTarget(ctx, tx, targetID)
+	if err != nil {
+		return nil, err
+	}
+
+	var deletedAt *time.Time
+	err = tx.QueryRowContext(ctx, `SELECT deleted_at FROM posts WHERE id = $1 FOR SHARE`, postID).Scan(&deletedAt)
+	if err != nil {
+		return nil, wrapDBError(err, "failed to lock post")
+	}
+	if deletedAt != nil {
+		return nil, ErrPostDeleted
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO bookmarks (user_id, target_id, created_at)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (user_id, target_id) DO NOTHING
+	`, userID, targetID, now())
+	if err != nil {
+		return nil, wrapDBError(err, "failed to save bookmark")
+	}
+
+	bookmark := &model.Bookmark{UserID: userID, TargetID: targetID}
+	err = tx.QueryRowContext(ctx, `SELECT created_at FROM bookmarks WHERE user_id = $1 AND target_id = $2`,
+		userID, targetID).Scan(&bookmark.CreatedAt)
+	if err != nil {
+		return nil, wrapDBError(err, "failed to fetch bookmark")
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, wrapDBError(err, "failed to commit bookmark")
+	}
+
+	return bookmark, r.fillBookmarkTargets(ctx, []*model.Bookmark{bookmark})
+}
+
+// RemoveBookmark убирает запись из закладок пользователя
+func (r *PostgresRepository) RemoveBookmark(ctx context.Context, userID, targetID string) (*model.Bookmark, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	bookmark := &model.Bookmark{UserID: userID, TargetID: targetID}
+	err := r.db.QueryRowContext(ctx, `
+		DELETE FROM bookmarks WHERE user_id = $1 AND target_id = $2
+		RETURNING created_at
+	`, userID, targetID).Scan(&bookmark.CreatedAt)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return nil, wrapDBError(err, "failed to delete bookmark")
+	}
+
+	if err := r.fillBookmarkTargets(ctx, []*model.Bookmark{bookmark}); err != nil {
+		return nil, err
+	}
+	if bookmark.Post == nil && bookmark.Comment == nil {
+		return nil, NotFound("пост или комментарий не найден")
+	}
+
+	return bookmark, nil
+}
+
+// GetBookmarks возвращает страницу закладок пользователя (индекс idx_bookmarks_user)
+func (r *PostgresRepository) GetBookmarks(ctx context.Context, userID string, first int, after *model.Cursor) (*model.Page[*model.Bookmark], error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	query := `SELECT user_id, target_id, created_at FROM bookmarks WHERE user_id = $1`
+	args := []interface{}{userID}
+	if after != nil {
+		query += fmt.Sprintf(` AND (created_at, target_id) < ($%d::timestamp, $%d::uuid)`, len(args)+1, len(args)+2)
+		args = append(args, after.CreatedAt.UTC(), after.ID)
+	}
+	query += fmt.Sprintf(` ORDER BY created_at DESC, target_id DESC LIMIT $%d`, len(args)+1)
+	// Лишняя запись показывает, есть ли следующая страница
+	args = append(args, first+1)
+
+	bookmarks := []*model.Bookmark{}
+	if err := r.db.SelectContext(ctx, &bookmarks, query, args...); err != nil {
+		return nil, wrapDBError(err, "failed to fetch bookmarks")
+	}
+
+	page := &model.Page[*model.Bookmark]{Items: bookmarks}
+	if len(bookmarks) > first {
+		page.Items = bookmarks[:first]
+		page.HasNextPage = true
+	}
+
+	if err := r.fillBookmarkTargets(ctx, page.Items); err != nil {
+		return nil, err
+	}
+
+	err := r.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM bookmarks WHERE user_id = $1`, userID).Scan(&page.TotalCount)
+	if err != nil {
+		return nil, wrapDBError(err, "failed to count bookmarks")
+	}
+
+	return page, nil
+}
+
+// fillBookmarkTargets загружает сохранённые записи двумя запросами
+func (r *PostgresRepository) fillBookmarkTargets(ctx context.Context, bookmarks []*model.Bookmark) error {
+	ids := make([]string, len(bookmarks))
+	for i, b := range bookmarks {
+		ids[i] = b.TargetID
+	}
+
+	posts, err := r.postsByIDs(ctx, ids)
+	if err != nil {
+		return err
+	}
+
+	var commentIDs []string
+	for _, b := range bookmarks {
+		if b.Post = posts[b.TargetID]; b.Post == nil {
+			commentIDs = append(commentIDs, b.TargetID)
+		}
+	}
+
+	comments, err := r.commentsByIDs(ctx, commentIDs)
+	if err != nil {
+		return err
+	}
+	for _, b := range bookmarks {
+		if b.Post == nil {
+			b.Comment = comments[b.TargetID]
+		}
+	}
+
+	return nil
+}
+
+// GetBookmarked сообщает, какие записи в закладках пользователя
+func (r *PostgresRepository) GetBookmarked(ctx context.Context, userID string, targetIDs []string) (map[string]bool, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	bookmarked := make(map[string]bool, len(targetIDs))
+	if len(targetIDs) == 0 {
+		return bookmarked, nil
+	}
+
+	var ids []string
+	err := r.db.SelectContext(ctx, &ids, `
+		SELECT target_id
+		FROM bookmarks
+		WHERE user_id = $1 AND target_id = ANY($2::uuid[])
+	`, userID, pq.Array(targetIDs))
+	if err != nil {
+		return nil, wrapDBError(err, "failed to fetch bookmarks")
+	}
+
+	for _, id := range ids {
+		bookmarked[id] = true
+	}
+
+	return bookmarked, nil
 }
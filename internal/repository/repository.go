@@ -2,6 +2,7 @@ package repository
 
 import (
 	"context"
+	"time"
 
 	"github.com/22Fariz22/forum/internal/model"
 )
@@ -9,19 +10,158 @@ import (
 // Repository – интерфейс для работы с постами и комментариями
 type Repository interface {
 	//методы для пользователя
-	CreateUser(user *model.User) error
-	GetUserByID(id string) (*model.User, error)
+	CreateUser(ctx context.Context, user *model.User) error
+	GetUserByID(ctx context.Context, id string) (*model.User, error)
 
 	// Методы для постов
-	CreatePost(post *model.Post) error
-	GetPosts(offset int32, limit int32) ([]*model.Post, error)
-	GetPostByID(id string) (*model.Post, error)
+	CreatePost(ctx context.Context, post *model.Post) error
+	// Лента постов: filter отбирает посты раздела и с заданными тегами
+	GetPosts(ctx context.Context, order model.SortOrder, filter model.PostFilter, offset int32, limit int32) ([]*model.Post, error)
+	GetPostByID(ctx context.Context, id string) (*model.Post, error)
+	// Включает или отключает комментарии к посту, возвращает обновлённый пост
+	SetCommentsEnabled(ctx context.Context, postID string, enabled bool) (*model.Post, error)
+	// Меняет заголовок и/или текст поста (nil – без изменений) и отмечает EditedAt.
+	// Удалённый пост изменить нельзя: ErrPostDeleted
+	UpdatePost(ctx context.Context, postID, editorID string, title, content *string) (*model.Post, error)
+	// Мягко удаляет пост: заголовок и текст заменяются на model.DeletedContent,
+	// комментарии закрываются, пост пропадает из ленты. Повторное удаление – не ошибка
+	DeletePost(ctx context.Context, postID, editorID string) (*model.Post, error)
 
-	// Методы для комментариев
+	// Разделы форума. CreateForum добавляет раздел в конец списка, название
+	// уникально: Conflict. ReorderForums принимает ID всех разделов в новом
+	// порядке, иначе Validation. GetForums возвращает разделы по Position
+	CreateForum(ctx context.Context, forum *model.Forum) error
+	GetForums(ctx context.Context) ([]*model.Forum, error)
+	GetForumByID(ctx context.Context, id string) (*model.Forum, error)
+	ReorderForums(ctx context.Context, ids []string) ([]*model.Forum, error)
+	// Самые частые теги неудалённых постов (раздела forumID, если он не пуст):
+	// по убыванию числа постов, при равенстве по имени
+	GetTags(ctx context.Context, forumID string, first int) ([]*model.Tag, error)
+
+	// Методы для комментариев. Если комментарии к посту отключены,
+	// CreateCommentOnPost и ReplyToComment возвращают ErrCommentsDisabled
 	CreateCommentOnPost(ctx context.Context, comment *model.Comment) (*model.Comment, error)
 	ReplyToComment(ctx context.Context, comment *model.Comment) (*model.Comment, error)
-	GetReplies(parentID string, offset, limit int) ([]*model.Comment, error)
+	GetReplies(ctx context.Context, parentID string, order model.SortOrder, offset, limit int) ([]*model.Comment, error)
+	GetCommentByID(ctx context.Context, id string) (*model.Comment, error)
+	// Комментарии поста всех уровней после курсора, по возрастанию (created_at, id)
+	GetCommentsAfter(ctx context.Context, postID string, after model.Cursor, limit int) ([]*model.Comment, error)
+	// Неудалённые комментарии поста, изменённые после курсора model.EditCursorOf,
+	// по возрастанию (edited_at, id)
+	GetCommentsEditedAfter(ctx context.Context, postID string, after model.Cursor, limit int) ([]*model.Comment, error)
+	// Меняет текст комментария и отмечает EditedAt. Заглушку изменить нельзя: ErrCommentDeleted
+	UpdateComment(ctx context.Context, commentID, editorID, content string) (*model.Comment, error)
+	// Удаляет комментарий: с ответами он остаётся в ветке заглушкой model.DeletedContent,
+	// без ответов удаляется совсем вместе с ревизиями. Возвращает заглушку с DeletedAt
+	DeleteComment(ctx context.Context, commentID, editorID string) (*model.Comment, error)
+
+	// Ревизии. UpdatePost, DeletePost, UpdateComment и DeleteComment сохраняют
+	// прежнюю версию в той же операции, editorID – автор изменения.
+	// GetRevisions возвращает прежние версии поста или комментария от старых к новым
+	GetRevisions(ctx context.Context, entityID string) ([]*model.Revision, error)
+	GetRevisionByID(ctx context.Context, id string) (*model.Revision, error)
 
 	// // Получаем комментарии верхнего уровня для поста с пагинацией
-	GetCommentsByPostID(postID string, limit, offset int) ([]*model.Comment, error)
+	GetCommentsByPostID(ctx context.Context, postID string, order model.SortOrder, offset, limit int) ([]*model.Comment, error)
+
+	// Пакетная загрузка для dataloader: страница offset/limit отдельно для каждого
+	// поста (комментарии верхнего уровня) или родителя (ответы) в порядке order
+	GetCommentsByPostIDs(ctx context.Context, postIDs []string, order model.SortOrder, offset, limit int) (map[string][]*model.Comment, error)
+	GetRepliesByParentIDs(ctx context.Context, parentIDs []string, order model.SortOrder, offset, limit int) (map[string][]*model.Comment, error)
+
+	// Дерево комментариев поста: уровни 0..maxDepth, на каждом уровне не больше
+	// perLevelLimit ответов на один комментарий (и комментариев верхнего уровня),
+	// от старых к новым. Пропущенные ответы учитываются в TruncatedReplies.
+	GetCommentTree(ctx context.Context, postID string, maxDepth, perLevelLimit int) (*model.CommentTree, error)
+
+	// Все комментарии поста в порядке обхода ветки в глубину (по материализованному
+	// пути): first комментариев с путём строго больше after, after == "" – с начала
+	GetThreadPage(ctx context.Context, postID string, first int, after string) (*model.Page[*model.Comment], error)
+
+	// Keyset-пагинация в порядке order: first элементов строго после курсора after,
+	// after == nil – с начала списка. Курсор строится model.PostCursor или
+	// model.CommentCursor и должен относиться к тому же порядку. Оба хранилища
+	// сортируют одинаково: см. model.SortOrder
+	GetPostsPage(ctx context.Context, order model.SortOrder, filter model.PostFilter, first int, after *model.Cursor) (*model.Page[*model.Post], error)
+	GetCommentsPage(ctx context.Context, postID string, order model.SortOrder, first int, after *model.Cursor) (*model.Page[*model.Comment], error)
+	GetRepliesPage(ctx context.Context, parentID string, order model.SortOrder, first int, after *model.Cursor) (*model.Page[*model.Comment], error)
+
+	// Голосование. Vote ставит голос пользователя за пост или комментарий:
+	// 1 – за, -1 – против, 0 – отменить голос. Голоса за одну запись применяются
+	// по очереди, Upvotes, Downvotes и Score меняются в той же операции.
+	// Удалённые пост или комментарий: ErrPostDeleted, ErrCommentDeleted
+	Vote(ctx context.Context, userID, targetID string, value int) (*model.Votable, error)
+	// Голоса пользователя за записи targetIDs; записей без голоса нет в результате
+	GetVotes(ctx context.Context, userID string, targetIDs []string) (map[string]int, error)
+
+	// Эмодзи-реакции. AddReaction и RemoveReaction идемпотентны: повторная
+	// реакция или снятие отсутствующей не меняют данных (Changed = false).
+	// Допустимость эмодзи проверяет вызывающий код.
+	// Удалённые пост или комментарий: ErrPostDeleted, ErrCommentDeleted
+	AddReaction(ctx context.Context, userID, targetID, emoji string) (*model.ReactionChange, error)
+	RemoveReaction(ctx context.Context, userID, targetID, emoji string) (*model.ReactionChange, error)
+	// Закладки. AddBookmark сохраняет пост или комментарий в закладки пользователя,
+	// RemoveBookmark убирает; оба идемпотентны и возвращают закладку с записью.
+	// Удалённые пост или комментарий сохранить нельзя: ErrPostDeleted, ErrCommentDeleted,
+	// комментарий удалённого поста – ErrPostDeleted. При удалении записи закладки на неё
+	// удаляются, при удалении поста – и закладки на его комментарии
+	AddBookmark(ctx context.Context, userID, targetID string) (*model.Bookmark, error)
+	RemoveBookmark(ctx context.Context, userID, targetID string) (*model.Bookmark, error)
+	// Закладки пользователя от новых к старым, курсор – Bookmark.Cursor
+	GetBookmarks(ctx context.Context, userID string, first int, after *model.Cursor) (*model.Page[*model.Bookmark], error)
+	// Какие из записей targetIDs в закладках пользователя; остальных нет в результате
+	GetBookmarked(ctx context.Context, userID string, targetIDs []string) (map[string]bool, error)
+
+	// Реакции на записи targetIDs в порядке первой реакции каждого вида.
+	// ViewerReacted заполняется для userID, пустой userID – анонимный пользователь
+	GetReactions(ctx context.Context, userID string, targetIDs []string) (map[string][]*model.ReactionCount, error)
+
+	// Полнотекстовый поиск по постам и комментариям в синтаксисе websearch_to_tsquery.
+	// Результаты идут по убыванию веса, курсор – SearchHit.Cursor. Удалённые
+	// посты и комментарии, а также комментарии удалённых постов не находятся
+	Search(ctx context.Context, query string, kind model.SearchKind, first int, after *model.Cursor) (*model.Page[*model.SearchHit], error)
+}
+
+// now возвращает текущее время в UTC с точностью PostgreSQL (микросекунды),
+// чтобы курсоры совпадали в обоих хранилищах
+func now() time.Time {
+	return time.Now().UTC().Truncate(time.Microsecond)
+}
+
+// voteDelta – изменение числа голосов за и против при замене голоса old на value
+func voteDelta(old, value int) (up, down int) {
+	switch old {
+	case 1:
+		up--
+	case -1:
+		down--
+	}
+	switch value {
+	case 1:
+		up++
+	case -1:
+		down++
+	}
+	return up, down
+}
+
+// checkForumOrder проверяет, что ids содержит каждый из total существующих
+// разделов ровно один раз
+func checkForumOrder(ids []string, total int, exists func(id string) bool) error {
+	if len(ids) != total {
+		return Validation("нужно перечислить все разделы")
+	}
+
+	seen := make(map[string]bool, len(ids))
+	for _, id := range ids {
+		if seen[id] {
+			return Validation("раздел указан несколько раз")
+		}
+		if !exists(id) {
+			return NotFound("раздел не найден")
+		}
+		seen[id] = true
+	}
+
+	return nil
 }
@@ -1,13 +1,21 @@
 package server
 
 import (
+	"context"
 	"net/http"
+	"strings"
 
 	"github.com/22Fariz22/forum/config"
 	"github.com/22Fariz22/forum/graph"
+	"github.com/22Fariz22/forum/internal/auth"
 	"github.com/22Fariz22/forum/pkg/logger"
 	"github.com/99designs/gqlgen/graphql/handler"
+	"github.com/99designs/gqlgen/graphql/handler/extension"
+	"github.com/99designs/gqlgen/graphql/handler/lru"
+	"github.com/99designs/gqlgen/graphql/handler/transport"
 	"github.com/99designs/gqlgen/graphql/playground"
+	"github.com/gorilla/websocket"
+	"github.com/vektah/gqlparser/v2/ast"
 )
 
 // Server struct
@@ -23,13 +31,65 @@ func NewServer(logger logger.Logger, cfg *config.Config, resolver *graph.Resolve
 }
 
 func (s *Server) Run() {
-	srv := handler.NewDefaultServer(graph.NewExecutableSchema(graph.Config{Resolvers: s.resolver}))
+	srv := handler.New(graph.NewExecutableSchema(graph.Config{Resolvers: s.resolver}))
+
+	// WebSocket для подписок: gqlgen сам договаривается о протоколе
+	// graphql-transport-ws или устаревшем graphql-ws
+	srv.AddTransport(transport.Websocket{
+		KeepAlivePingInterval: s.cfg.Server.WsKeepAliveInterval,
+		Upgrader: websocket.Upgrader{
+			CheckOrigin: func(r *http.Request) bool {
+				return true
+			},
+		},
+		InitFunc: websocketInit,
+	})
+	srv.AddTransport(transport.Options{})
+	srv.AddTransport(transport.GET{})
+	// SSE (GraphQL over Server-Sent Events) для клиентов за прокси, которые
+	// не пропускают WebSocket. Регистрируется до POST: оба принимают JSON
+	// POST-запросы, а SSE отличается заголовком Accept: text/event-stream
+	srv.AddTransport(transport.SSE{
+		KeepAlivePingInterval: s.cfg.Server.SseKeepAliveInterval,
+	})
+	srv.AddTransport(transport.POST{})
+	srv.AddTransport(transport.MultipartForm{})
+
+	srv.SetQueryCache(lru.New[*ast.QueryDocument](1000))
+	srv.SetErrorPresenter(graph.NewErrorPresenter(s.logger))
+	// Загрузчики живут одну операцию и объединяют запросы вложенных полей
+	srv.AroundOperations(graph.LoadersMiddleware(s.resolver.Repo))
+
+	srv.Use(extension.Introspection{})
+	srv.Use(extension.AutomaticPersistedQuery{
+		Cache: lru.New[string](100),
+	})
 
 	http.Handle("/", playground.Handler("GraphQL playground", "/query"))
-	http.Handle("/query", srv)
+	http.Handle("/query", auth.Middleware(disableProxyBuffering(srv)))
 
 	addr := ":" + s.cfg.Server.Port
 
 	s.logger.Infof("Сервер запущен на %s", addr)
 	s.logger.Error(http.ListenAndServe(addr, nil))
 }
+
+// disableProxyBuffering просит nginx-подобные прокси не буферизовать поток SSE,
+// иначе события и heartbeat-комментарии доходят до клиента пачками
+func disableProxyBuffering(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.Contains(r.Header.Get("Accept"), "text/event-stream") {
+			w.Header().Set("X-Accel-Buffering", "no")
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// websocketInit берёт ID пользователя из connection_init: браузер не может
+// передать собственные заголовки при открытии WebSocket
+func websocketInit(ctx context.Context, payload transport.InitPayload) (context.Context, *transport.InitPayload, error) {
+	if userID, ok := payload[auth.InitPayloadKey].(string); ok {
+		ctx = auth.WithUserID(ctx, userID)
+	}
+	return ctx, nil, nil
+}
@@ -0,0 +1,235 @@
+// Package validation проверяет пользовательский ввод мутаций. Правила задаются
+// для вида значения (заголовок поста, текст комментария, ...) и могут
+// дополняться через Register. Длина считается в символах Unicode, а не в байтах.
+package validation
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// Kind – вид проверяемого значения
+type Kind string
+
+const (
+	Username         Kind = "username"
+	PostTitle        Kind = "postTitle"
+	PostContent      Kind = "postContent"
+	CommentContent   Kind = "commentContent"
+	SearchQuery      Kind = "searchQuery"
+	Reaction         Kind = "reaction"
+	ForumName        Kind = "forumName"
+	ForumDescription Kind = "forumDescription"
+	Tag              Kind = "tag"
+)
+
+// Rule проверяет значение и возвращает сообщение об ошибке или ""
+type Rule func(value string) string
+
+// Limits – настраиваемые ограничения стандартных правил
+type Limits struct {
+	UsernameMinLength int
+	UsernameMaxLength int
+	// Допустимые символы имени пользователя
+	UsernamePattern      string
+	TitleMaxLength       int
+	PostMaxLength        int
+	CommentMaxLength     int
+	SearchQueryMaxLength int
+	// Разрешённые эмодзи-реакции
+	Reactions []string
+	// Название не длиннее столбца forums.name (50)
+	ForumNameMaxLength        int
+	ForumDescriptionMaxLength int
+	TagMaxLength              int
+	// Число тегов у поста
+	TagsMaxCount int
+}
+
+// DefaultLimits – ограничения по умолчанию. Длины имени и заголовка
+// не превышают ширину столбцов users.username и posts.title.
+var DefaultLimits = Limits{
+	UsernameMinLength:    2,
+	UsernameMaxLength:    20,
+	UsernamePattern:      `^[\p{L}\p{N}_.-]+$`,
+	TitleMaxLength:       50,
+	PostMaxLength:        20000,
+	CommentMaxLength:     2000,
+	SearchQueryMaxLength: 200,
+	Reactions:            []string{"👍", "❤️", "😂", "🎉", "😮", "😢"},
+
+	ForumNameMaxLength:        50,
+	ForumDescriptionMaxLength: 500,
+	TagMaxLength:              32,
+	TagsMaxCount:              5,
+}
+
+// tagPattern – допустимые теги: буквы и цифры, внутри также _ + # . -
+var tagPattern = regexp.MustCompile(`^[\p{L}\p{N}][\p{L}\p{N}_+#.-]*$`)
+
+// Validator хранит правила для каждого вида значений
+type Validator struct {
+	rules        map[Kind][]Rule
+	tagsMaxCount int
+}
+
+// New создаёт валидатор со стандартными правилами
+func New(limits Limits) (*Validator, error) {
+	pattern, err := regexp.Compile(limits.UsernamePattern)
+	if err != nil {
+		return nil, fmt.Errorf("username pattern: %w", err)
+	}
+
+	v := &Validator{rules: make(map[Kind][]Rule), tagsMaxCount: limits.TagsMaxCount}
+	v.Register(Username,
+		MinLength(limits.UsernameMinLength),
+		MaxLength(limits.UsernameMaxLength),
+		Matches(pattern, "содержит недопустимые символы"),
+	)
+	v.Register(PostTitle, NotBlank(), MaxLength(limits.TitleMaxLength))
+	v.Register(PostContent, NotBlank(), MaxLength(limits.PostMaxLength))
+	v.Register(CommentContent, NotBlank(), MaxLength(limits.CommentMaxLength))
+	v.Register(SearchQuery, NotBlank(), MaxLength(limits.SearchQueryMaxLength))
+	v.Register(Reaction, OneOf(limits.Reactions, "недопустимая реакция"))
+	v.Register(ForumName, NotBlank(), MaxLength(limits.ForumNameMaxLength))
+	v.Register(ForumDescription, MaxLength(limits.ForumDescriptionMaxLength))
+	v.Register(Tag,
+		NotBlank(),
+		MaxLength(limits.TagMaxLength),
+		Matches(tagPattern, "содержит недопустимые символы"),
+	)
+
+	return v, nil
+}
+
+// Register добавляет правила для вида значений. Правила проверяются по порядку
+// до первой ошибки.
+func (v *Validator) Register(kind Kind, rules ...Rule) {
+	v.rules[kind] = append(v.rules[kind], rules...)
+}
+
+// Field – проверяемое поле: имя аргумента мутации, вид и значение
+type Field struct {
+	Name  string
+	Kind  Kind
+	Value *string // nil – поле не передано и не проверяется
+}
+
+// Value описывает обязательное поле
+func Value(name string, kind Kind, value string) Field {
+	return Field{Name: name, Kind: kind, Value: &value}
+}
+
+// Optional описывает необязательное поле
+func Optional(name string, kind Kind, value *string) Field {
+	return Field{Name: name, Kind: kind, Value: value}
+}
+
+// Validate проверяет поля и возвращает Errors со всеми нарушениями или nil
+func (v *Validator) Validate(fields ...Field) error {
+	errs := Errors{}
+	for _, f := range fields {
+		if f.Value == nil {
+			continue
+		}
+		for _, rule := range v.rules[f.Kind] {
+			if msg := rule(*f.Value); msg != "" {
+				errs[f.Name] = msg
+				break
+			}
+		}
+	}
+
+	if len(errs) == 0 {
+		return nil
+	}
+	return errs
+}
+
+// ValidateTags проверяет число тегов и каждый тег правилами Tag.
+// Ошибка относится к полю name
+func (v *Validator) ValidateTags(name string, tags []string) error {
+	if len(tags) > v.tagsMaxCount {
+		return Errors{name: fmt.Sprintf("не больше %d тегов", v.tagsMaxCount)}
+	}
+	for _, tag := range tags {
+		if err := v.Validate(Value(name, Tag, tag)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Errors – ошибки по полям: имя аргумента мутации → сообщение
+type Errors map[string]string
+
+func (e Errors) Error() string {
+	names := make([]string, 0, len(e))
+	for name := range e {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	parts := make([]string, 0, len(names))
+	for _, name := range names {
+		parts = append(parts, name+": "+e[name])
+	}
+	return strings.Join(parts, "; ")
+}
+
+// NotBlank запрещает пустые значения и значения только из пробелов
+func NotBlank() Rule {
+	return func(value string) string {
+		if strings.TrimSpace(value) == "" {
+			return "не может быть пустым"
+		}
+		return ""
+	}
+}
+
+// MinLength ограничивает длину снизу (в символах)
+func MinLength(n int) Rule {
+	return func(value string) string {
+		if utf8.RuneCountInString(value) < n {
+			return fmt.Sprintf("должно быть не короче %d символов", n)
+		}
+		return ""
+	}
+}
+
+// MaxLength ограничивает длину сверху (в символах)
+func MaxLength(n int) Rule {
+	return func(value string) string {
+		if utf8.RuneCountInString(value) > n {
+			return fmt.Sprintf("должно быть не длиннее %d символов", n)
+		}
+		return ""
+	}
+}
+
+// Matches требует совпадения с регулярным выражением
+func Matches(re *regexp.Regexp, message string) Rule {
+	return func(value string) string {
+		if !re.MatchString(value) {
+			return message
+		}
+		return ""
+	}
+}
+
+// OneOf требует, чтобы значение совпадало с одним из допустимых
+func OneOf(values []string, message string) Rule {
+	allowed := make(map[string]bool, len(values))
+	for _, v := range values {
+		allowed[v] = true
+	}
+	return func(value string) string {
+		if !allowed[value] {
+			return message
+		}
+		return ""
+	}
+}
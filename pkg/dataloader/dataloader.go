@@ -0,0 +1,113 @@
+package dataloader
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultWait – сколько ждать остальные ключи перед запросом
+	DefaultWait = time.Millisecond
+	// DefaultMaxBatch – наибольшее число ключей в одном запросе
+	DefaultMaxBatch = 100
+)
+
+// BatchFunc загружает значения сразу для нескольких ключей.
+// Ключи, которых нет в результате, получают нулевое значение.
+type BatchFunc[K comparable, V any] func(ctx context.Context, keys []K) (map[K]V, error)
+
+// Options – настройки загрузчика
+type Options struct {
+	Wait     time.Duration
+	MaxBatch int
+}
+
+// Loader собирает ключи, запрошенные параллельно работающими резолверами,
+// и загружает их одним вызовом BatchFunc. Результаты не кешируются между
+// пакетами, поэтому загрузчик безопасен и для долгих подписок.
+type Loader[K comparable, V any] struct {
+	fetch BatchFunc[K, V]
+	opts  Options
+
+	mu    sync.Mutex
+	batch *batch[K, V] // собираемый пакет, nil – пакета нет
+}
+
+// batch – пакет ключей, загружаемых одним вызовом
+type batch[K comparable, V any] struct {
+	ctx    context.Context
+	keys   []K
+	seen   map[K]struct{}
+	once   sync.Once
+	done   chan struct{}
+	result map[K]V
+	err    error
+}
+
+// New создаёт загрузчик с заданной функцией пакетной загрузки
+func New[K comparable, V any](fetch BatchFunc[K, V], opts Options) *Loader[K, V] {
+	if opts.Wait <= 0 {
+		opts.Wait = DefaultWait
+	}
+	if opts.MaxBatch <= 0 {
+		opts.MaxBatch = DefaultMaxBatch
+	}
+
+	return &Loader[K, V]{fetch: fetch, opts: opts}
+}
+
+// Load возвращает значение для ключа, дожидаясь загрузки его пакета
+func (l *Loader[K, V]) Load(ctx context.Context, key K) (V, error) {
+	l.mu.Lock()
+
+	b := l.batch
+	if b == nil {
+		b = &batch[K, V]{
+			ctx:  ctx,
+			seen: make(map[K]struct{}),
+			done: make(chan struct{}),
+		}
+		l.batch = b
+		time.AfterFunc(l.opts.Wait, func() { l.dispatch(b) })
+	}
+
+	if _, ok := b.seen[key]; !ok {
+		b.seen[key] = struct{}{}
+		b.keys = append(b.keys, key)
+	}
+
+	// Заполненный пакет уходит сразу, не дожидаясь таймера
+	if len(b.keys) >= l.opts.MaxBatch {
+		l.batch = nil
+		go l.dispatch(b)
+	}
+
+	l.mu.Unlock()
+
+	select {
+	case <-b.done:
+		if b.err != nil {
+			var zero V
+			return zero, b.err
+		}
+		return b.result[key], nil
+	case <-ctx.Done():
+		var zero V
+		return zero, ctx.Err()
+	}
+}
+
+// dispatch закрывает пакет для новых ключей и загружает его
+func (l *Loader[K, V]) dispatch(b *batch[K, V]) {
+	l.mu.Lock()
+	if l.batch == b {
+		l.batch = nil
+	}
+	l.mu.Unlock()
+
+	b.once.Do(func() {
+		b.result, b.err = l.fetch(b.ctx, b.keys)
+		close(b.done)
+	})
+}
@@ -17,5 +17,123 @@ func Migrate(logger logger.Logger, dsn string) error {
 	}
 
 	// Выполнение миграций
-	return db.AutoMigrate(&model.User{}, &model.Post{}, &model.Comment{})
+	if err := db.AutoMigrate(&model.User{}, &model.Post{}, &model.Comment{}, &model.Revision{}, &model.Vote{}, &model.Reaction{}, &model.Forum{}, &model.Bookmark{}); err != nil {
+		return err
+	}
+
+	// Заполняем материализованные пути комментариев, созданных до их появления
+	if err := backfillCommentPaths(db); err != nil {
+		logger.Debugf("Error in comment paths backfill")
+		return err
+	}
+
+	// Столбцы, которые GORM не отображает
+	for _, stmt := range columns {
+		if err := db.Exec(stmt).Error; err != nil {
+			logger.Debugf("Error in column migration: %s", stmt)
+			return err
+		}
+	}
+
+	// Столбцы полнотекстового поиска: генерируются из текста, поэтому
+	// обновляются при любой вставке и правке и заполняются для старых строк
+	for _, stmt := range searchColumns {
+		if err := db.Exec(stmt).Error; err != nil {
+			logger.Debugf("Error in search column migration: %s", stmt)
+			return err
+		}
+	}
+
+	// Индексы, которые не выражаются через теги GORM
+	for _, stmt := range indexes {
+		if err := db.Exec(stmt).Error; err != nil {
+			logger.Debugf("Error in index migration: %s", stmt)
+			return err
+		}
+	}
+
+	return nil
+}
+
+// columns – столбцы типов, которых нет в GORM
+var columns = []string{
+	// Теги поста в нижнем регистре, см. model.NormalizeTags
+	`ALTER TABLE posts ADD COLUMN IF NOT EXISTS tags text[] NOT NULL DEFAULT '{}'`,
+}
+
+// searchColumns – tsvector-столбцы для поиска. Конфигурация "russian" совпадает
+// с repository.searchConfig; заголовок поста весит больше текста (A и B)
+var searchColumns = []string{
+	`ALTER TABLE posts ADD COLUMN IF NOT EXISTS search_vector tsvector GENERATED ALWAYS AS (
+		setweight(to_tsvector('russian', coalesce(title, '')), 'A') ||
+		setweight(to_tsvector('russian', coalesce(content, '')), 'B')
+	) STORED`,
+	`ALTER TABLE comments ADD COLUMN IF NOT EXISTS search_vector tsvector GENERATED ALWAYS AS (
+		setweight(to_tsvector('russian', coalesce(content, '')), 'B')
+	) STORED`,
+}
+
+// indexes – дополнительные индексы
+var indexes = []string{
+	// Лента комментариев поста по (created_at, id): курсоры подписок
+	`CREATE INDEX IF NOT EXISTS idx_comments_post_created ON comments (post_id, created_at, id)`,
+	// Правки комментариев поста по (edited_at, id): курсоры подписки commentUpdated
+	`CREATE INDEX IF NOT EXISTS idx_comments_post_edited ON comments (post_id, edited_at, id) WHERE edited_at IS NOT NULL`,
+	// Keyset-пагинация постов, комментариев верхнего уровня и ответов
+	`CREATE INDEX IF NOT EXISTS idx_posts_created ON posts (created_at, id)`,
+	`CREATE INDEX IF NOT EXISTS idx_comments_top_created ON comments (post_id, created_at, id) WHERE parent_id IS NULL`,
+	`CREATE INDEX IF NOT EXISTS idx_comments_parent_created ON comments (parent_id, created_at, id)`,
+	// Обход ветки в глубину: пути сравниваются побайтово
+	`CREATE INDEX IF NOT EXISTS idx_comments_thread ON comments (post_id, path COLLATE "C")`,
+	// Порядки TOP и MOST_REPLIED ленты постов; HOT считается выражением и сортируется в запросе
+	`CREATE INDEX IF NOT EXISTS idx_posts_score ON posts (score DESC, created_at DESC, id DESC) WHERE deleted_at IS NULL`,
+	`CREATE INDEX IF NOT EXISTS idx_posts_comment_count ON posts (comment_count DESC, created_at DESC, id DESC) WHERE deleted_at IS NULL`,
+	// Полнотекстовый поиск
+	`CREATE INDEX IF NOT EXISTS idx_posts_search ON posts USING GIN (search_vector)`,
+	`CREATE INDEX IF NOT EXISTS idx_comments_search ON comments USING GIN (search_vector)`,
+	// История изменений поста или комментария
+	`CREATE INDEX IF NOT EXISTS idx_revisions_entity ON revisions (entity_id, created_at, id)`,
+	// Голоса за запись: пересчёт счётчиков и удаление вместе с комментарием
+	`CREATE INDEX IF NOT EXISTS idx_votes_target ON votes (target_id, value)`,
+	// Реакции на запись по видам; первичный ключ начинается с user_id и не подходит
+	`CREATE INDEX IF NOT EXISTS idx_reactions_target ON reactions (target_id, emoji)`,
+	// Лента раздела и отбор по тегам
+	`CREATE INDEX IF NOT EXISTS idx_posts_forum ON posts (forum_id, created_at DESC, id DESC) WHERE deleted_at IS NULL`,
+	`CREATE INDEX IF NOT EXISTS idx_posts_tags ON posts USING GIN (tags)`,
+	// Закладки пользователя от новых к старым и удаление вместе с записью
+	`CREATE INDEX IF NOT EXISTS idx_bookmarks_user ON bookmarks (user_id, created_at DESC, target_id DESC)`,
+	`CREATE INDEX IF NOT EXISTS idx_bookmarks_target ON bookmarks (target_id)`,
+}
+
+// backfillCommentPaths вычисляет path и depth для комментариев без пути.
+// Сегмент пути совпадает с model.PathSegment: 16 hex-цифр времени создания
+// в микросекундах и UUID комментария.
+func backfillCommentPaths(db *gorm.DB) error {
+	var missing int64
+	if err := db.Model(&model.Comment{}).Where("path IS NULL OR path = ''").Count(&missing).Error; err != nil {
+		return err
+	}
+	if missing == 0 {
+		return nil
+	}
+
+	return db.Exec(`
+		WITH RECURSIVE tree AS (
+			SELECT id,
+				lpad(to_hex((extract(epoch FROM created_at) * 1000000)::bigint), 16, '0') || id::text AS path,
+				0 AS depth
+			FROM comments
+			WHERE parent_id IS NULL
+			UNION ALL
+			SELECT c.id,
+				tree.path || lpad(to_hex((extract(epoch FROM c.created_at) * 1000000)::bigint), 16, '0') || c.id::text,
+				tree.depth + 1
+			FROM comments c
+			JOIN tree ON c.parent_id = tree.id
+		)
+		UPDATE comments
+		SET path = tree.path, depth = tree.depth
+		FROM tree
+		WHERE comments.id = tree.id AND (comments.path IS NULL OR comments.path = '')
+	`).Error
 }
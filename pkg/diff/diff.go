@@ -0,0 +1,116 @@
+// Package diff строит построчную разницу двух текстов алгоритмом Майерса
+package diff
+
+import "strings"
+
+// Op – вид строки в разнице
+type Op int
+
+const (
+	// Equal – строка есть в обоих текстах
+	Equal Op = iota
+	// Insert – строка есть только во втором тексте
+	Insert
+	// Delete – строка есть только в первом тексте
+	Delete
+)
+
+// Line – строка разницы
+type Line struct {
+	Op   Op
+	Text string
+}
+
+// Lines возвращает кратчайшую построчную разницу между a и b:
+// строки a с пометкой Delete, строки b с пометкой Insert и общие строки с Equal
+func Lines(a, b string) []Line {
+	return compute(split(a), split(b))
+}
+
+// split делит текст на строки. Пустой текст не содержит строк.
+func split(s string) []string {
+	if s == "" {
+		return nil
+	}
+	return strings.Split(strings.ReplaceAll(s, "\r\n", "\n"), "\n")
+}
+
+// compute ищет кратчайший путь редактирования, сохраняя состояние каждого шага
+// для обратного прохода
+func compute(a, b []string) []Line {
+	n, m := len(a), len(b)
+	max := n + m
+	offset := max + 1
+
+	// v[offset+k] – наибольший x на диагонали k после d правок
+	v := make([]int, 2*max+3)
+	var trace [][]int
+
+	for d := 0; d <= max; d++ {
+		trace = append(trace, append([]int(nil), v...))
+
+		for k := -d; k <= d; k += 2 {
+			var x int
+			if k == -d || (k != d && v[offset+k-1] < v[offset+k+1]) {
+				x = v[offset+k+1]
+			} else {
+				x = v[offset+k-1] + 1
+			}
+			y := x - k
+
+			for x < n && y < m && a[x] == b[y] {
+				x++
+				y++
+			}
+			v[offset+k] = x
+
+			if x >= n && y >= m {
+				return backtrack(trace, a, b, offset)
+			}
+		}
+	}
+
+	return nil
+}
+
+// backtrack восстанавливает путь от конца текстов к началу
+func backtrack(trace [][]int, a, b []string, offset int) []Line {
+	var lines []Line
+	x, y := len(a), len(b)
+
+	for d := len(trace) - 1; d >= 0; d-- {
+		v := trace[d]
+		k := x - y
+
+		var prevK int
+		if k == -d || (k != d && v[offset+k-1] < v[offset+k+1]) {
+			prevK = k + 1
+		} else {
+			prevK = k - 1
+		}
+		prevX := v[offset+prevK]
+		prevY := prevX - prevK
+
+		for x > prevX && y > prevY {
+			lines = append(lines, Line{Op: Equal, Text: a[x-1]})
+			x--
+			y--
+		}
+
+		if d > 0 {
+			if x == prevX {
+				lines = append(lines, Line{Op: Insert, Text: b[y-1]})
+			} else {
+				lines = append(lines, Line{Op: Delete, Text: a[x-1]})
+			}
+		}
+
+		x, y = prevX, prevY
+	}
+
+	for i, j := 0, len(lines)-1; i < j; i, j = i+1, j-1 {
+		lines[i], lines[j] = lines[j], lines[i]
+	}
+
+	return lines
+}
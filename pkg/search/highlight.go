@@ -0,0 +1,66 @@
+package search
+
+import "strings"
+
+// Маркеры совпадений во фрагменте: символы из области частного использования
+// Unicode не встречаются в обычном тексте и не конфликтуют с HTML. Те же
+// маркеры передаются в ts_headline, поэтому оба хранилища дают одинаковый формат
+const (
+	MarkStart = "\uE000"
+	MarkStop  = "\uE001"
+)
+
+// SnippetWords – максимальная длина фрагмента в словах (MaxWords у ts_headline)
+const SnippetWords = 35
+
+// Highlight возвращает фрагмент текста вокруг первого совпадения с запросом,
+// не длиннее maxWords слов, с найденными словами между MarkStart и MarkStop
+func Highlight(text string, q Query, maxWords int) string {
+	ws := words(text)
+	terms := q.terms()
+
+	first := -1
+	for i, w := range ws {
+		if terms[w.Term] {
+			first = i
+			break
+		}
+	}
+
+	// Несколько слов контекста перед совпадением
+	start, end := 0, len(ws)
+	if len(ws) > maxWords {
+		if first > maxWords/5 {
+			start = first - maxWords/5
+		}
+		if start+maxWords < end {
+			end = start + maxWords
+		} else {
+			start = end - maxWords
+		}
+	}
+
+	from, to := 0, len(text)
+	if start > 0 {
+		from = ws[start].Start
+	}
+	if end < len(ws) {
+		to = ws[end-1].End
+	}
+
+	var b strings.Builder
+	pos := from
+	for _, w := range ws[start:end] {
+		if !terms[w.Term] {
+			continue
+		}
+		b.WriteString(text[pos:w.Start])
+		b.WriteString(MarkStart)
+		b.WriteString(text[w.Start:w.End])
+		b.WriteString(MarkStop)
+		pos = w.End
+	}
+	b.WriteString(text[pos:to])
+
+	return strings.TrimSpace(b.String())
+}
@@ -0,0 +1,169 @@
+package search
+
+import "math"
+
+// Веса полей, как у setweight в PostgreSQL: A – заголовок, B – текст
+const (
+	WeightA = 1.0
+	WeightB = 0.4
+)
+
+// Field – индексируемое поле документа с весом
+type Field struct {
+	Text   string
+	Weight float64
+}
+
+// Match – найденный документ и его вес
+type Match struct {
+	ID   string
+	Rank float64
+}
+
+// occurrence – вхождение слова: позиция в документе и вес поля
+type occurrence struct {
+	pos    int
+	weight float64
+}
+
+// Index – инвертированный индекс: для каждого слова – документы и позиции.
+// Не потокобезопасен, синхронизация лежит на владельце.
+type Index struct {
+	postings map[string]map[string][]occurrence
+	docs     map[string][]string // ID документа → его слова, для удаления
+}
+
+// NewIndex создаёт пустой индекс
+func NewIndex() *Index {
+	return &Index{
+		postings: make(map[string]map[string][]occurrence),
+		docs:     make(map[string][]string),
+	}
+}
+
+// Add индексирует документ, заменяя прежнюю версию. Позиции полей идут
+// подряд, как при склейке tsvector оператором ||
+func (ix *Index) Add(id string, fields ...Field) {
+	ix.Remove(id)
+
+	offset := 0
+	for _, f := range fields {
+		ws := words(f.Text)
+		for _, w := range ws {
+			if w.Term == "" {
+				continue
+			}
+			docs := ix.postings[w.Term]
+			if docs == nil {
+				docs = make(map[string][]occurrence)
+				ix.postings[w.Term] = docs
+			}
+			if docs[id] == nil {
+				ix.docs[id] = append(ix.docs[id], w.Term)
+			}
+			docs[id] = append(docs[id], occurrence{pos: offset + w.Pos, weight: f.Weight})
+		}
+		offset += len(ws)
+	}
+}
+
+// Remove удаляет документ из индекса
+func (ix *Index) Remove(id string) {
+	for _, term := range ix.docs[id] {
+		delete(ix.postings[term], id)
+		if len(ix.postings[term]) == 0 {
+			delete(ix.postings, term)
+		}
+	}
+	delete(ix.docs, id)
+}
+
+// Search возвращает документы, подходящие хотя бы под один вариант запроса.
+// Вес – сумма log2(1 + сумма весов вхождений) по искомым словам запроса,
+// так что слова из заголовка и повторы поднимают документ выше.
+// Порядок результатов не определён.
+func (ix *Index) Search(q Query) []Match {
+	found := make(map[string]bool)
+	for _, group := range q.groups {
+		for _, id := range ix.candidates(group) {
+			if ix.matchGroup(id, group) {
+				found[id] = true
+			}
+		}
+	}
+
+	terms := q.terms()
+	matches := make([]Match, 0, len(found))
+	for id := range found {
+		rank := 0.0
+		for term := range terms {
+			weight := 0.0
+			for _, o := range ix.postings[term][id] {
+				weight += o.weight
+			}
+			rank += math.Log2(1 + weight)
+		}
+		matches = append(matches, Match{ID: id, Rank: rank})
+	}
+
+	return matches
+}
+
+// candidates – документы с самым редким искомым словом варианта
+func (ix *Index) candidates(group []clause) []string {
+	var rarest map[string][]occurrence
+	for _, c := range group {
+		if c.negate {
+			continue
+		}
+		docs := ix.postings[c.terms[0]]
+		if rarest == nil || len(docs) < len(rarest) {
+			rarest = docs
+		}
+		if len(rarest) == 0 {
+			return nil
+		}
+	}
+
+	ids := make([]string, 0, len(rarest))
+	for id := range rarest {
+		ids = append(ids, id)
+	}
+
+	return ids
+}
+
+// matchGroup проверяет, что документ содержит все искомые части варианта
+// и не содержит исключённых
+func (ix *Index) matchGroup(id string, group []clause) bool {
+	for _, c := range group {
+		if ix.matchClause(id, c) == c.negate {
+			return false
+		}
+	}
+	return true
+}
+
+// matchClause ищет слова части на позициях p+offsets[i]
+func (ix *Index) matchClause(id string, c clause) bool {
+	for _, start := range ix.postings[c.terms[0]][id] {
+		ok := true
+		for i := 1; i < len(c.terms) && ok; i++ {
+			ok = ix.hasAt(id, c.terms[i], start.pos+c.offsets[i])
+		}
+		if ok {
+			return true
+		}
+	}
+	return false
+}
+
+// hasAt сообщает, стоит ли слово term в документе на позиции pos
+func (ix *Index) hasAt(id, term string, pos int) bool {
+	for _, o := range ix.postings[term][id] {
+		if o.pos == pos {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,141 @@
+package search
+
+import (
+	"strings"
+	"unicode"
+)
+
+// Query – разобранный поисковый запрос в синтаксисе websearch_to_tsquery:
+// слова через пробел должны встретиться все, "фраза в кавычках" – подряд,
+// -слово исключает документы, or объединяет варианты.
+type Query struct {
+	groups [][]clause
+}
+
+// clause – слово или фраза запроса. offsets – позиции слов относительно
+// первого: стоп-слова внутри фразы оставляют промежуток
+type clause struct {
+	terms   []string
+	offsets []int
+	negate  bool
+}
+
+// ParseQuery разбирает запрос. Варианты без искомых слов (только стоп-слова
+// или только исключения) отбрасываются
+func ParseQuery(s string) Query {
+	var (
+		q     Query
+		group []clause
+	)
+
+	closeGroup := func() {
+		for _, c := range group {
+			if !c.negate {
+				q.groups = append(q.groups, group)
+				break
+			}
+		}
+		group = nil
+	}
+
+	for _, part := range splitQuery(s) {
+		if !part.quoted && strings.EqualFold(part.text, "or") {
+			closeGroup()
+			continue
+		}
+		if c, ok := newClause(part.text, part.negate); ok {
+			group = append(group, c)
+		}
+	}
+	closeGroup()
+
+	return q
+}
+
+// Empty сообщает, что запросу не соответствует ни один документ
+func (q Query) Empty() bool {
+	return len(q.groups) == 0
+}
+
+// terms возвращает все искомые (не исключённые) слова запроса
+func (q Query) terms() map[string]bool {
+	set := make(map[string]bool)
+	for _, group := range q.groups {
+		for _, c := range group {
+			if c.negate {
+				continue
+			}
+			for _, t := range c.terms {
+				set[t] = true
+			}
+		}
+	}
+	return set
+}
+
+// queryPart – слово или фраза запроса до токенизации
+type queryPart struct {
+	text   string
+	quoted bool
+	negate bool
+}
+
+// splitQuery делит запрос на части по пробелам и кавычкам
+func splitQuery(s string) []queryPart {
+	var parts []queryPart
+
+	runes := []rune(s)
+	for i := 0; i < len(runes); {
+		if unicode.IsSpace(runes[i]) {
+			i++
+			continue
+		}
+
+		part := queryPart{}
+		if runes[i] == '-' {
+			part.negate = true
+			i++
+		}
+
+		start := i
+		if i < len(runes) && runes[i] == '"' {
+			part.quoted = true
+			start = i + 1
+			i = start
+			for i < len(runes) && runes[i] != '"' {
+				i++
+			}
+			part.text = string(runes[start:i])
+			i++ // закрывающая кавычка
+		} else {
+			for i < len(runes) && !unicode.IsSpace(runes[i]) && runes[i] != '"' {
+				i++
+			}
+			part.text = string(runes[start:i])
+		}
+
+		parts = append(parts, part)
+	}
+
+	return parts
+}
+
+// newClause токенизирует часть запроса. Несколько слов (в кавычках или
+// через дефис) ищутся как фраза
+func newClause(text string, negate bool) (clause, bool) {
+	c := clause{negate: negate}
+
+	first := 0
+	for _, w := range words(text) {
+		if w.Term == "" {
+			continue
+		}
+		if first == 0 {
+			first = w.Pos
+		}
+		c.terms = append(c.terms, w.Term)
+		c.offsets = append(c.offsets, w.Pos-first)
+	}
+
+	return c, len(c.terms) > 0
+}
@@ -0,0 +1,123 @@
+// Package search – полнотекстовый поиск в памяти. Токенизация, стоп-слова и
+// стемминг повторяют конфигурацию PostgreSQL "russian": кириллические слова
+// обрабатываются русским стеммером Snowball, латинские – английским,
+// слова с цифрами не меняются.
+package search
+
+import (
+	"strings"
+	"unicode"
+
+	"github.com/blevesearch/snowballstem"
+	"github.com/blevesearch/snowballstem/english"
+	"github.com/blevesearch/snowballstem/russian"
+)
+
+// word – слово текста: байтовые границы в исходной строке, позиция
+// (считаются и стоп-слова, как в tsvector) и нормализованная форма.
+// Term пуст у стоп-слов.
+type word struct {
+	Start, End int
+	Pos        int
+	Term       string
+}
+
+// words разбивает текст на слова из букв и цифр. Позиции начинаются с 1
+func words(text string) []word {
+	var (
+		result []word
+		start  = -1
+	)
+
+	flush := func(end int) {
+		if start < 0 {
+			return
+		}
+		result = append(result, word{
+			Start: start,
+			End:   end,
+			Pos:   len(result) + 1,
+			Term:  Stem(text[start:end]),
+		})
+		start = -1
+	}
+
+	for i, r := range text {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			if start < 0 {
+				start = i
+			}
+			continue
+		}
+		flush(i)
+	}
+	flush(len(text))
+
+	return result
+}
+
+// Stem приводит слово к нормальной форме: нижний регистр, ё → е и основа
+// Snowball. Для стоп-слов возвращает ""
+func Stem(w string) string {
+	w = strings.ReplaceAll(strings.ToLower(w), "ё", "е")
+	if stopWords[w] {
+		return ""
+	}
+
+	var cyrillic, latin, digit bool
+	for _, r := range w {
+		switch {
+		case unicode.Is(unicode.Cyrillic, r):
+			cyrillic = true
+		case r < unicode.MaxASCII && unicode.IsLetter(r):
+			latin = true
+		case unicode.IsDigit(r):
+			digit = true
+		}
+	}
+
+	env := snowballstem.NewEnv(w)
+	switch {
+	case digit:
+		return w
+	case cyrillic:
+		russian.Stem(env)
+	case latin:
+		english.Stem(env)
+	default:
+		return w
+	}
+
+	return env.Current()
+}
+
+// stopWords – стоп-слова словарей russian и english PostgreSQL: они не
+// индексируются, но занимают позицию
+var stopWords = func() map[string]bool {
+	list := `и в во не что он на я с со как а то все она так его но да ты к у же
+		вы за бы по только ее мне было вот от меня еще нет о из ему теперь когда
+		даже ну вдруг ли если уже или ни быть был него до вас нибудь опять уж вам
+		ведь там потом себя ничего ей может они тут где есть надо ней для мы тебя
+		их чем была сам чтоб без будто чего раз тоже себе под будет ж тогда кто
+		этот того потому этого какой совсем ним здесь этом один почти мой тем
+		чтобы нее сейчас были куда зачем всех никогда можно при наконец два об
+		другой хоть после над больше тот через эти нас про всего них какая много
+		разве три эту моя впрочем хорошо свою этой перед иногда лучше чуть том
+		нельзя такой им более всегда конечно всю между
+
+		i me my myself we our ours ourselves you your yours yourself yourselves
+		he him his himself she her hers herself it its itself they them their
+		theirs themselves what which who whom this that these those am is are
+		was were be been being have has had having do does did doing a an the
+		and but if or because as until while of at by for with about against
+		between into through during before after above below to from up down in
+		out on off over under again further then once here there when where why
+		how all any both each few more most other some such no nor not only own
+		same so than too very s t can will just don should now`
+
+	set := make(map[string]bool)
+	for _, w := range strings.Fields(list) {
+		set[w] = true
+	}
+	return set
+}()
@@ -0,0 +1,232 @@
+package pubsub
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/22Fariz22/forum/pkg/logger"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+const (
+	// maxNotifyPayload – ограничение PostgreSQL на размер payload у NOTIFY
+	maxNotifyPayload = 8000
+
+	minReconnectInterval = time.Second
+	maxReconnectInterval = 30 * time.Second
+	listenerPingInterval = 90 * time.Second
+	notifyTimeout        = 5 * time.Second
+	fetchTimeout         = 5 * time.Second
+)
+
+// PostgresOptions – настройки PubSub поверх LISTEN/NOTIFY
+type PostgresOptions[T any] struct {
+	// Channel – имя канала NOTIFY, общее для всех реплик
+	Channel string
+	// Local – настройки очередей локальных подписчиков
+	Local Options
+	// ID возвращает идентификатор сообщения. Используется, если сообщение
+	// не помещается в payload NOTIFY и вместо него передаётся ссылка.
+	ID func(msg T) string
+	// Fetch загружает сообщение по идентификатору на принимающей стороне
+	Fetch func(ctx context.Context, id string) (T, error)
+}
+
+// envelope – содержимое payload уведомления
+type envelope struct {
+	Topic string          `json:"topic"`
+	Data  json.RawMessage `json:"data,omitempty"`
+	Ref   string          `json:"ref,omitempty"`
+}
+
+// PostgresPubSub рассылает сообщения всем репликам через LISTEN/NOTIFY.
+// Publish отправляет NOTIFY, а каждая реплика (включая отправителя) получает
+// уведомление и раздаёт его своим локальным подписчикам.
+type PostgresPubSub[T any] struct {
+	local    *PubSub[T]
+	db       *sqlx.DB
+	listener *pq.Listener
+	opts     PostgresOptions[T]
+	logger   logger.Logger
+	done     chan struct{}
+	stopped  chan struct{}
+	once     sync.Once
+}
+
+var _ Broker[any] = (*PostgresPubSub[any])(nil)
+
+// NewPostgres создаёт PubSub поверх LISTEN/NOTIFY. dsn используется для
+// отдельного соединения слушателя, который сам переподключается при обрывах.
+func NewPostgres[T any](db *sqlx.DB, dsn string, logger logger.Logger, opts PostgresOptions[T]) (*PostgresPubSub[T], error) {
+	if opts.Channel == "" {
+		return nil, errors.New("не задан канал NOTIFY")
+	}
+
+	ps := &PostgresPubSub[T]{
+		local:   New[T](opts.Local),
+		db:      db,
+		opts:    opts,
+		logger:  logger,
+		done:    make(chan struct{}),
+		stopped: make(chan struct{}),
+	}
+
+	ps.listener = pq.NewListener(dsn, minReconnectInterval, maxReconnectInterval, ps.onListenerEvent)
+	if err := ps.listener.Listen(opts.Channel); err != nil {
+		ps.listener.Close()
+		return nil, err
+	}
+
+	go ps.run()
+
+	return ps, nil
+}
+
+// Subscribe – подписка на определённую тему
+func (ps *PostgresPubSub[T]) Subscribe(topic string) (*Subscription[T], error) {
+	return ps.local.Subscribe(topic)
+}
+
+// Unsubscribe – отменяет подписку
+func (ps *PostgresPubSub[T]) Unsubscribe(sub *Subscription[T]) {
+	ps.local.Unsubscribe(sub)
+}
+
+// Publish – отправляет сообщение всем репликам через NOTIFY
+func (ps *PostgresPubSub[T]) Publish(topic string, msg T) {
+	payload, err := ps.encode(topic, msg)
+	if err != nil {
+		ps.logger.Errorf("pubsub: не удалось закодировать сообщение для %s: %v", topic, err)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), notifyTimeout)
+	defer cancel()
+
+	if _, err := ps.db.ExecContext(ctx, `SELECT pg_notify($1, $2)`, ps.opts.Channel, string(payload)); err != nil {
+		ps.logger.Errorf("pubsub: NOTIFY %s не выполнен: %v", ps.opts.Channel, err)
+	}
+}
+
+// encode упаковывает сообщение в payload. Если сообщение слишком большое,
+// передаётся только его идентификатор.
+func (ps *PostgresPubSub[T]) encode(topic string, msg T) ([]byte, error) {
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return nil, err
+	}
+
+	payload, err := json.Marshal(envelope{Topic: topic, Data: data})
+	if err != nil {
+		return nil, err
+	}
+	if len(payload) < maxNotifyPayload {
+		return payload, nil
+	}
+
+	if ps.opts.ID == nil || ps.opts.Fetch == nil {
+		return nil, errors.New("сообщение превышает лимит NOTIFY, а загрузка по ID не настроена")
+	}
+
+	return json.Marshal(envelope{Topic: topic, Ref: ps.opts.ID(msg)})
+}
+
+// run получает уведомления и раздаёт их локальным подписчикам
+func (ps *PostgresPubSub[T]) run() {
+	defer close(ps.stopped)
+
+	ticker := time.NewTicker(listenerPingInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ps.done:
+			return
+
+		case n, ok := <-ps.listener.Notify:
+			if !ok {
+				return
+			}
+			// nil приходит после переподключения: уведомления за время обрыва потеряны
+			if n == nil {
+				ps.logger.Warnf("pubsub: уведомления %s за время обрыва могли быть потеряны", ps.opts.Channel)
+				continue
+			}
+			ps.dispatch(n.Extra)
+
+		case <-ticker.C:
+			// Проверяем соединение, чтобы вовремя заметить его потерю
+			go func() {
+				if err := ps.listener.Ping(); err != nil {
+					ps.logger.Warnf("pubsub: ping слушателя %s: %v", ps.opts.Channel, err)
+				}
+			}()
+		}
+	}
+}
+
+// dispatch декодирует payload и публикует сообщение локально
+func (ps *PostgresPubSub[T]) dispatch(payload string) {
+	var env envelope
+	if err := json.Unmarshal([]byte(payload), &env); err != nil {
+		ps.logger.Errorf("pubsub: некорректный payload в %s: %v", ps.opts.Channel, err)
+		return
+	}
+
+	var msg T
+	if env.Ref != "" {
+		if ps.opts.Fetch == nil {
+			ps.logger.Errorf("pubsub: получена ссылка %s, но загрузка по ID не настроена", env.Ref)
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), fetchTimeout)
+		defer cancel()
+
+		fetched, err := ps.opts.Fetch(ctx, env.Ref)
+		if err != nil {
+			ps.logger.Errorf("pubsub: не удалось загрузить %s: %v", env.Ref, err)
+			return
+		}
+		msg = fetched
+	} else if err := json.Unmarshal(env.Data, &msg); err != nil {
+		ps.logger.Errorf("pubsub: некорректное сообщение в %s: %v", ps.opts.Channel, err)
+		return
+	}
+
+	ps.local.Publish(env.Topic, msg)
+}
+
+// onListenerEvent логирует состояние соединения слушателя
+func (ps *PostgresPubSub[T]) onListenerEvent(event pq.ListenerEventType, err error) {
+	switch event {
+	case pq.ListenerEventDisconnected:
+		ps.logger.Warnf("pubsub: слушатель %s отключён: %v", ps.opts.Channel, err)
+	case pq.ListenerEventReconnected:
+		ps.logger.Infof("pubsub: слушатель %s переподключён", ps.opts.Channel)
+	case pq.ListenerEventConnectionAttemptFailed:
+		ps.logger.Warnf("pubsub: не удалось подключить слушателя %s: %v", ps.opts.Channel, err)
+	}
+}
+
+// Stats возвращает счётчики локальных подписчиков
+func (ps *PostgresPubSub[T]) Stats() Stats {
+	return ps.local.Stats()
+}
+
+// Close останавливает слушателя и закрывает локальных подписчиков
+func (ps *PostgresPubSub[T]) Close() {
+	ps.once.Do(func() {
+		close(ps.done)
+		<-ps.stopped
+
+		if err := ps.listener.Close(); err != nil {
+			ps.logger.Warnf("pubsub: закрытие слушателя %s: %v", ps.opts.Channel, err)
+		}
+		ps.local.Close()
+	})
+}
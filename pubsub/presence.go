@@ -0,0 +1,167 @@
+package pubsub
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// Viewer – участник, присутствующий в теме. Пустой UserID – анонимный зритель.
+type Viewer struct {
+	UserID   string
+	Username string
+}
+
+// PresenceSnapshot – состояние присутствия в теме
+type PresenceSnapshot struct {
+	Topic     string
+	Count     int      // анонимные подключения + уникальные пользователи
+	Usernames []string // имена представившихся пользователей по алфавиту
+}
+
+// Presence отслеживает активные подписки по темам и сообщает об изменениях.
+// Уведомления сглаживаются: не чаще одного на тему за интервал debounce,
+// поэтому наплыв зрителей в большую ветку не вызывает лавину рассылок.
+// Учитываются только подписки текущего процесса.
+type Presence struct {
+	mu       sync.Mutex
+	topics   map[string]*presenceTopic
+	nextID   uint64
+	debounce time.Duration
+	notify   func(snapshot PresenceSnapshot)
+	closed   bool
+}
+
+type presenceTopic struct {
+	viewers map[uint64]Viewer
+	timer   *time.Timer
+}
+
+// NewPresence создаёт трекер присутствия. notify вызывается с итоговым
+// состоянием темы после каждой серии изменений.
+func NewPresence(debounce time.Duration, notify func(snapshot PresenceSnapshot)) *Presence {
+	return &Presence{
+		topics:   make(map[string]*presenceTopic),
+		debounce: debounce,
+		notify:   notify,
+	}
+}
+
+// Join отмечает зрителя в теме. Возвращённую функцию нужно вызвать, когда
+// подписка завершится; повторные вызовы безопасны.
+func (p *Presence) Join(topic string, viewer Viewer) (leave func()) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	t, ok := p.topics[topic]
+	if !ok {
+		t = &presenceTopic{viewers: make(map[uint64]Viewer)}
+		p.topics[topic] = t
+	}
+
+	p.nextID++
+	id := p.nextID
+	t.viewers[id] = viewer
+	p.schedule(topic, t)
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			p.leave(topic, id)
+		})
+	}
+}
+
+// leave убирает зрителя из темы
+func (p *Presence) leave(topic string, id uint64) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	t, ok := p.topics[topic]
+	if !ok {
+		return
+	}
+
+	delete(t.viewers, id)
+	p.schedule(topic, t)
+}
+
+// Snapshot возвращает текущее состояние темы
+func (p *Presence) Snapshot(topic string) PresenceSnapshot {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return p.snapshot(topic)
+}
+
+// schedule откладывает уведомление об изменении темы. Вызывается под мьютексом.
+func (p *Presence) schedule(topic string, t *presenceTopic) {
+	if p.closed || t.timer != nil {
+		return
+	}
+
+	t.timer = time.AfterFunc(p.debounce, func() {
+		p.flush(topic)
+	})
+}
+
+// flush рассылает накопившееся изменение темы
+func (p *Presence) flush(topic string) {
+	p.mu.Lock()
+	t, ok := p.topics[topic]
+	if !ok || p.closed {
+		p.mu.Unlock()
+		return
+	}
+
+	t.timer = nil
+	snapshot := p.snapshot(topic)
+
+	// Пустая тема больше не нужна
+	if len(t.viewers) == 0 {
+		delete(p.topics, topic)
+	}
+	p.mu.Unlock()
+
+	p.notify(snapshot)
+}
+
+// snapshot считает состояние темы. Вызывается под мьютексом.
+func (p *Presence) snapshot(topic string) PresenceSnapshot {
+	snapshot := PresenceSnapshot{Topic: topic, Usernames: []string{}}
+
+	t, ok := p.topics[topic]
+	if !ok {
+		return snapshot
+	}
+
+	users := make(map[string]string)
+	for _, v := range t.viewers {
+		if v.UserID == "" {
+			snapshot.Count++
+			continue
+		}
+		users[v.UserID] = v.Username
+	}
+
+	snapshot.Count += len(users)
+	for _, name := range users {
+		snapshot.Usernames = append(snapshot.Usernames, name)
+	}
+	sort.Strings(snapshot.Usernames)
+
+	return snapshot
+}
+
+// Close останавливает отложенные уведомления
+func (p *Presence) Close() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.closed = true
+	for _, t := range p.topics {
+		if t.timer != nil {
+			t.timer.Stop()
+		}
+	}
+}
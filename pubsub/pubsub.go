@@ -1,53 +1,243 @@
 package pubsub
 
-import "sync"
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+)
 
-// Subscriber – канал для отправки сообщений подписчику
-type Subscriber chan interface{}
+// ErrClosed возвращается при подписке на уже закрытый PubSub
+var ErrClosed = errors.New("pubsub закрыт")
 
-// PubSub реализует базовый механизм pub/sub
-type PubSub struct {
-	mu          sync.RWMutex
-	subscribers map[string][]Subscriber
+// OverflowPolicy определяет, что делать, если буфер подписчика заполнен
+type OverflowPolicy int
+
+const (
+	// DropOldest вытесняет самое старое сообщение из буфера
+	DropOldest OverflowPolicy = iota
+	// DropNewest отбрасывает новое сообщение
+	DropNewest
+	// Disconnect отключает медленного подписчика (его канал закрывается)
+	Disconnect
+)
+
+// Broker – общий интерфейс реализаций pubsub (в памяти процесса или через PostgreSQL)
+type Broker[T any] interface {
+	Subscribe(topic string) (*Subscription[T], error)
+	Unsubscribe(sub *Subscription[T])
+	Publish(topic string, msg T)
+	Close()
+}
+
+// DefaultBufferSize – размер буфера подписчика по умолчанию
+const DefaultBufferSize = 16
+
+// Options – настройки очередей подписчиков
+type Options struct {
+	BufferSize int
+	Overflow   OverflowPolicy
+}
+
+// Stats – счётчики PubSub
+type Stats struct {
+	Published    uint64 // сколько сообщений опубликовано
+	Dropped      uint64 // сколько сообщений отброшено из-за переполнения
+	Disconnected uint64 // сколько подписчиков отключено из-за переполнения
+	Subscribers  int    // текущее число подписчиков
+}
+
+// Subscription – подписка на тему с собственным ограниченным буфером
+type Subscription[T any] struct {
+	topic  string
+	ch     chan T
+	closed bool // защищено мьютексом PubSub
+}
+
+// Topic возвращает тему подписки
+func (s *Subscription[T]) Topic() string {
+	return s.topic
+}
+
+// C возвращает канал сообщений. Канал закрывается при отписке,
+// отключении медленного подписчика или закрытии PubSub.
+func (s *Subscription[T]) C() <-chan T {
+	return s.ch
+}
+
+var _ Broker[any] = (*PubSub[any])(nil)
+
+// PubSub реализует pub/sub в пределах процесса с типизированными сообщениями.
+// Все отправки и закрытия каналов выполняются под одним мьютексом,
+// поэтому отправка в закрытый канал невозможна.
+type PubSub[T any] struct {
+	mu          sync.Mutex
+	opts        Options
+	subscribers map[string]map[*Subscription[T]]struct{}
+	closed      bool
+
+	published    atomic.Uint64
+	dropped      atomic.Uint64
+	disconnected atomic.Uint64
 }
 
-func NewPubSub() *PubSub {
-	return &PubSub{
-		subscribers: make(map[string][]Subscriber),
+// New создаёт PubSub с заданными настройками
+func New[T any](opts Options) *PubSub[T] {
+	if opts.BufferSize <= 0 {
+		opts.BufferSize = DefaultBufferSize
+	}
+
+	return &PubSub[T]{
+		opts:        opts,
+		subscribers: make(map[string]map[*Subscription[T]]struct{}),
 	}
 }
 
 // Subscribe – подписка на определённую тему
-func (ps *PubSub) Subscribe(topic string) Subscriber {
+func (ps *PubSub[T]) Subscribe(topic string) (*Subscription[T], error) {
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+
+	if ps.closed {
+		return nil, ErrClosed
+	}
+
+	sub := &Subscription[T]{
+		topic: topic,
+		ch:    make(chan T, ps.opts.BufferSize),
+	}
+
+	if ps.subscribers[topic] == nil {
+		ps.subscribers[topic] = make(map[*Subscription[T]]struct{})
+	}
+	ps.subscribers[topic][sub] = struct{}{}
+
+	return sub, nil
+}
+
+// Unsubscribe – отменяет подписку. Повторный вызов безопасен.
+func (ps *PubSub[T]) Unsubscribe(sub *Subscription[T]) {
 	ps.mu.Lock()
 	defer ps.mu.Unlock()
-	ch := make(Subscriber)
-	ps.subscribers[topic] = append(ps.subscribers[topic], ch)
-	return ch
+
+	ps.remove(sub)
 }
 
-// Unsubscribe – отменяет подписку
-func (ps *PubSub) Unsubscribe(topic string, sub Subscriber) {
+// Publish – публикует сообщение в определённую тему. Никогда не блокируется:
+// при заполненном буфере подписчика применяется OverflowPolicy.
+func (ps *PubSub[T]) Publish(topic string, msg T) {
 	ps.mu.Lock()
 	defer ps.mu.Unlock()
-	subs := ps.subscribers[topic]
-	for i, s := range subs {
-		if s == sub {
-			ps.subscribers[topic] = append(subs[:i], subs[i+1:]...)
-			close(s)
-			break
+
+	if ps.closed {
+		return
+	}
+
+	ps.published.Add(1)
+
+	for sub := range ps.subscribers[topic] {
+		ps.deliver(sub, msg)
+	}
+}
+
+// deliver кладёт сообщение в буфер подписчика. Вызывается под мьютексом.
+func (ps *PubSub[T]) deliver(sub *Subscription[T], msg T) {
+	select {
+	case sub.ch <- msg:
+		return
+	default:
+	}
+
+	switch ps.opts.Overflow {
+	case DropNewest:
+		ps.dropped.Add(1)
+
+	case Disconnect:
+		ps.dropped.Add(1)
+		ps.disconnected.Add(1)
+		ps.remove(sub)
+
+	default: // DropOldest
+		// Читатель может параллельно освобождать место, поэтому
+		// вытесняем старое сообщение, только если буфер всё ещё полон
+		for {
+			select {
+			case sub.ch <- msg:
+				return
+			default:
+			}
+
+			select {
+			case <-sub.ch:
+				ps.dropped.Add(1)
+			default:
+			}
+		}
+	}
+}
+
+// remove удаляет подписчика и закрывает его канал. Вызывается под мьютексом.
+func (ps *PubSub[T]) remove(sub *Subscription[T]) {
+	if sub == nil || sub.closed {
+		return
+	}
+
+	if subs, ok := ps.subscribers[sub.topic]; ok {
+		delete(subs, sub)
+		if len(subs) == 0 {
+			delete(ps.subscribers, sub.topic)
 		}
 	}
+
+	sub.closed = true
+	close(sub.ch)
+}
+
+// Close закрывает каналы всех подписчиков. Последующие Publish игнорируются,
+// а Subscribe возвращает ErrClosed.
+func (ps *PubSub[T]) Close() {
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+
+	if ps.closed {
+		return
+	}
+	ps.closed = true
+
+	for _, subs := range ps.subscribers {
+		for sub := range subs {
+			ps.remove(sub)
+		}
+	}
+}
+
+// Stats возвращает текущие значения счётчиков
+func (ps *PubSub[T]) Stats() Stats {
+	ps.mu.Lock()
+	subscribers := 0
+	for _, subs := range ps.subscribers {
+		subscribers += len(subs)
+	}
+	ps.mu.Unlock()
+
+	return Stats{
+		Published:    ps.published.Load(),
+		Dropped:      ps.dropped.Load(),
+		Disconnected: ps.disconnected.Load(),
+		Subscribers:  subscribers,
+	}
 }
 
-// Publish – публикует сообщение в определённую тему
-func (ps *PubSub) Publish(topic string, msg interface{}) {
-	ps.mu.RLock()
-	defer ps.mu.RUnlock()
-	for _, sub := range ps.subscribers[topic] {
-		// Отправляем сообщение в горутине, чтобы не блокировать публикацию
-		go func(s Subscriber) {
-			s <- msg
-		}(sub)
+// ParseOverflowPolicy разбирает политику переполнения из строки конфигурации
+func ParseOverflowPolicy(s string) (OverflowPolicy, error) {
+	switch s {
+	case "drop-oldest", "":
+		return DropOldest, nil
+	case "drop-newest":
+		return DropNewest, nil
+	case "disconnect":
+		return Disconnect, nil
+	default:
+		return DropOldest, fmt.Errorf("неизвестная политика переполнения: %q", s)
 	}
 }
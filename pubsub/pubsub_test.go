@@ -0,0 +1,270 @@
+package pubsub
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+// drain читает из канала всё, что в нём уже лежит
+func drain[T any](ch <-chan T) []T {
+	var got []T
+	for {
+		select {
+		case msg, ok := <-ch:
+			if !ok {
+				return got
+			}
+			got = append(got, msg)
+		default:
+			return got
+		}
+	}
+}
+
+// isClosed сообщает, закрыт ли пустой канал
+func isClosed[T any](ch <-chan T) bool {
+	select {
+	case _, ok := <-ch:
+		return !ok
+	case <-time.After(time.Second):
+		return false
+	}
+}
+
+func TestPublishDelivers(t *testing.T) {
+	ps := New[int](Options{BufferSize: 4})
+	defer ps.Close()
+
+	sub, err := ps.Subscribe("a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	other, err := ps.Subscribe("b")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ps.Publish("a", 1)
+	ps.Publish("a", 2)
+
+	if got := drain(sub.C()); len(got) != 2 || got[0] != 1 || got[1] != 2 {
+		t.Fatalf("тема a: получено %v, ожидалось [1 2]", got)
+	}
+	if got := drain(other.C()); len(got) != 0 {
+		t.Fatalf("тема b: получено %v, ожидалось пусто", got)
+	}
+}
+
+func TestOverflowPolicies(t *testing.T) {
+	tests := []struct {
+		name         string
+		policy       OverflowPolicy
+		want         []int
+		closed       bool
+		dropped      uint64
+		disconnected uint64
+	}{
+		{name: "DropOldest", policy: DropOldest, want: []int{3, 4}, dropped: 2},
+		{name: "DropNewest", policy: DropNewest, want: []int{1, 2}, dropped: 2},
+		{name: "Disconnect", policy: Disconnect, want: []int{1, 2}, closed: true, dropped: 1, disconnected: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ps := New[int](Options{BufferSize: 2, Overflow: tt.policy})
+			defer ps.Close()
+
+			sub, err := ps.Subscribe("t")
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			for i := 1; i <= 4; i++ {
+				ps.Publish("t", i)
+			}
+
+			got := drain(sub.C())
+			if len(got) != len(tt.want) {
+				t.Fatalf("получено %v, ожидалось %v", got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Fatalf("получено %v, ожидалось %v", got, tt.want)
+				}
+			}
+			if tt.closed && !isClosed(sub.C()) {
+				t.Fatal("канал медленного подписчика не закрыт")
+			}
+
+			stats := ps.Stats()
+			if stats.Published != 4 {
+				t.Errorf("Published = %d, ожидалось 4", stats.Published)
+			}
+			if stats.Dropped != tt.dropped {
+				t.Errorf("Dropped = %d, ожидалось %d", stats.Dropped, tt.dropped)
+			}
+			if stats.Disconnected != tt.disconnected {
+				t.Errorf("Disconnected = %d, ожидалось %d", stats.Disconnected, tt.disconnected)
+			}
+			wantSubscribers := 1
+			if tt.closed {
+				wantSubscribers = 0
+			}
+			if stats.Subscribers != wantSubscribers {
+				t.Errorf("Subscribers = %d, ожидалось %d", stats.Subscribers, wantSubscribers)
+			}
+		})
+	}
+}
+
+// publishLoop публикует сообщения, пока не закрыт stop
+func publishLoop(ps *PubSub[int], topic string, stop <-chan struct{}, wg *sync.WaitGroup) {
+	defer wg.Done()
+	for i := 0; ; i++ {
+		select {
+		case <-stop:
+			return
+		default:
+			ps.Publish(topic, i)
+		}
+	}
+}
+
+func TestUnsubscribeDuringPublish(t *testing.T) {
+	for _, policy := range []OverflowPolicy{DropOldest, DropNewest, Disconnect} {
+		ps := New[int](Options{BufferSize: 1, Overflow: policy})
+
+		stop := make(chan struct{})
+		var wg sync.WaitGroup
+		for i := 0; i < 4; i++ {
+			wg.Add(1)
+			go publishLoop(ps, "t", stop, &wg)
+		}
+
+		var subs sync.WaitGroup
+		for i := 0; i < 50; i++ {
+			sub, err := ps.Subscribe("t")
+			if err != nil {
+				t.Fatal(err)
+			}
+			subs.Add(1)
+			go func() {
+				defer subs.Done()
+				// Читатель освобождает буфер одновременно с вытеснением
+				for range sub.C() {
+				}
+			}()
+			ps.Unsubscribe(sub)
+			// Повторная отписка безопасна
+			ps.Unsubscribe(sub)
+		}
+		subs.Wait()
+
+		close(stop)
+		wg.Wait()
+
+		if n := ps.Stats().Subscribers; n != 0 {
+			t.Fatalf("политика %d: осталось %d подписчиков", policy, n)
+		}
+		ps.Close()
+	}
+}
+
+func TestCloseDuringPublish(t *testing.T) {
+	ps := New[int](Options{BufferSize: 1})
+
+	subs := make([]*Subscription[int], 10)
+	for i := range subs {
+		sub, err := ps.Subscribe("t")
+		if err != nil {
+			t.Fatal(err)
+		}
+		subs[i] = sub
+	}
+
+	stop := make(chan struct{})
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go publishLoop(ps, "t", stop, &wg)
+	}
+
+	ps.Close()
+	close(stop)
+	wg.Wait()
+
+	for i, sub := range subs {
+		drain(sub.C())
+		if !isClosed(sub.C()) {
+			t.Fatalf("канал подписчика %d не закрыт", i)
+		}
+	}
+	if n := ps.Stats().Subscribers; n != 0 {
+		t.Fatalf("после Close осталось %d подписчиков", n)
+	}
+}
+
+func TestPublishAfterClose(t *testing.T) {
+	ps := New[int](Options{})
+	sub, err := ps.Subscribe("t")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ps.Close()
+	ps.Close() // повторное закрытие безопасно
+	ps.Publish("t", 1)
+
+	if got := drain(sub.C()); len(got) != 0 {
+		t.Fatalf("после Close получено %v", got)
+	}
+	if n := ps.Stats().Published; n != 0 {
+		t.Fatalf("Published = %d, публикации после Close не считаются", n)
+	}
+	if _, err := ps.Subscribe("t"); err != ErrClosed {
+		t.Fatalf("Subscribe после Close: %v, ожидалось ErrClosed", err)
+	}
+	// Отписка после Close не паникует на закрытом канале
+	ps.Unsubscribe(sub)
+}
+
+func TestStatsSubscribers(t *testing.T) {
+	ps := New[int](Options{})
+	defer ps.Close()
+
+	a, _ := ps.Subscribe("a")
+	_, _ = ps.Subscribe("a")
+	_, _ = ps.Subscribe("b")
+	if n := ps.Stats().Subscribers; n != 3 {
+		t.Fatalf("Subscribers = %d, ожидалось 3", n)
+	}
+
+	ps.Unsubscribe(a)
+	if n := ps.Stats().Subscribers; n != 2 {
+		t.Fatalf("Subscribers = %d, ожидалось 2", n)
+	}
+
+	ps.Publish("a", 1)
+	ps.Publish("c", 1) // тема без подписчиков тоже считается
+	if n := ps.Stats().Published; n != 2 {
+		t.Fatalf("Published = %d, ожидалось 2", n)
+	}
+}
+
+func TestParseOverflowPolicy(t *testing.T) {
+	for s, want := range map[string]OverflowPolicy{
+		"":            DropOldest,
+		"drop-oldest": DropOldest,
+		"drop-newest": DropNewest,
+		"disconnect":  Disconnect,
+	} {
+		got, err := ParseOverflowPolicy(s)
+		if err != nil || got != want {
+			t.Errorf("ParseOverflowPolicy(%q) = %v, %v; ожидалось %v", s, got, err, want)
+		}
+	}
+	if _, err := ParseOverflowPolicy("block"); err == nil {
+		t.Error("ожидалась ошибка для неизвестной политики")
+	}
+}
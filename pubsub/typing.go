@@ -0,0 +1,122 @@
+package pubsub
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrRateLimited – пользователь сообщает о наборе текста слишком часто
+var ErrRateLimited = errors.New("слишком частые запросы")
+
+// TypingEvent – пользователь начал или перестал набирать ответ
+type TypingEvent struct {
+	PostID   string
+	ParentID string // пустой – ответ на сам пост
+	Viewer   Viewer
+	Active   bool
+}
+
+// Typing хранит эфемерные индикаторы набора текста. Индикатор гаснет сам,
+// если его не обновили за ttl. Ничего не сохраняется в хранилище.
+type Typing struct {
+	mu          sync.Mutex
+	ttl         time.Duration
+	minInterval time.Duration
+	entries     map[typingKey]*typingEntry
+	lastTouch   map[string]time.Time // key=userID, для ограничения частоты
+	notify      func(event TypingEvent)
+	closed      bool
+}
+
+type typingKey struct {
+	postID string
+	userID string
+}
+
+type typingEntry struct {
+	event TypingEvent
+	timer *time.Timer
+}
+
+// NewTyping создаёт трекер. notify вызывается, когда пользователь начинает
+// набирать текст (или переключается на другой комментарий) и когда индикатор гаснет.
+func NewTyping(ttl, minInterval time.Duration, notify func(event TypingEvent)) *Typing {
+	return &Typing{
+		ttl:         ttl,
+		minInterval: minInterval,
+		entries:     make(map[typingKey]*typingEntry),
+		lastTouch:   make(map[string]time.Time),
+		notify:      notify,
+	}
+}
+
+// Touch отмечает, что пользователь набирает ответ в посте. Повторные вызовы
+// продлевают индикатор; слишком частые возвращают ErrRateLimited.
+func (t *Typing) Touch(postID, parentID string, viewer Viewer) error {
+	t.mu.Lock()
+
+	now := time.Now()
+	if last, ok := t.lastTouch[viewer.UserID]; ok && now.Sub(last) < t.minInterval {
+		t.mu.Unlock()
+		return ErrRateLimited
+	}
+	t.lastTouch[viewer.UserID] = now
+
+	key := typingKey{postID: postID, userID: viewer.UserID}
+	event := TypingEvent{PostID: postID, ParentID: parentID, Viewer: viewer, Active: true}
+
+	entry, ok := t.entries[key]
+	if ok && entry.event.ParentID == parentID {
+		// Тот же индикатор – просто продлеваем
+		entry.timer.Reset(t.ttl)
+		t.mu.Unlock()
+		return nil
+	}
+
+	if ok {
+		entry.timer.Stop()
+	}
+
+	entry = &typingEntry{event: event}
+	entry.timer = time.AfterFunc(t.ttl, func() {
+		t.expire(key, entry)
+	})
+	t.entries[key] = entry
+	t.mu.Unlock()
+
+	t.notify(event)
+	return nil
+}
+
+// expire гасит индикатор, который не обновляли дольше ttl
+func (t *Typing) expire(key typingKey, entry *typingEntry) {
+	t.mu.Lock()
+	// Индикатор могли заменить, пока срабатывал таймер
+	if t.closed || t.entries[key] != entry {
+		t.mu.Unlock()
+		return
+	}
+	delete(t.entries, key)
+
+	// Отметки о частоте старше minInterval больше не нужны
+	if last, ok := t.lastTouch[key.userID]; ok && time.Since(last) >= t.minInterval {
+		delete(t.lastTouch, key.userID)
+	}
+	t.mu.Unlock()
+
+	event := entry.event
+	event.Active = false
+	t.notify(event)
+}
+
+// Close останавливает таймеры индикаторов
+func (t *Typing) Close() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.closed = true
+	for _, entry := range t.entries {
+		entry.timer.Stop()
+	}
+}
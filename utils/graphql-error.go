@@ -1,6 +1,8 @@
 package utils
 
-import "github.com/vektah/gqlparser/v2/gqlerror"
+import (
+	"github.com/vektah/gqlparser/v2/gqlerror"
+)
 
 // Функция для создания ошибки с кодом
 func NewGraphQLError(message string, code string) *gqlerror.Error {